@@ -340,6 +340,43 @@ func (c *Client) GetUserTracks(accessToken string, userID string) ([]model.Track
 	return allTracks, nil
 }
 
+// Get a single track by ID
+func (c *Client) GetTrack(accessToken string, trackID string) (*model.Track, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/tracks/%s", SoundCloudAPIBase, url.PathEscape(trackID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("OAuth %s", accessToken))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get track failed: %s", string(body))
+	}
+
+	var scTrack SCTrack
+	if err := json.NewDecoder(resp.Body).Decode(&scTrack); err != nil {
+		return nil, err
+	}
+
+	return &model.Track{
+		Uri:        scTrack.PermalinkURL,
+		DurationMs: scTrack.Duration,
+		Isrc:       "",
+		ArtistsId:  []string{fmt.Sprintf("%d", scTrack.User.ID)},
+		ID:         fmt.Sprintf("%d", scTrack.ID),
+	}, nil
+}
+
 // SoundCloud Playlist
 type SCPlaylist struct {
 	ID           int    `json:"id"`
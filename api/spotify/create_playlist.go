@@ -2,7 +2,6 @@ package spotify
 
 import (
 	"context"
-	"strconv"
 
 	"github.com/pp-develop/music-timer-api/model"
 	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
@@ -13,7 +12,7 @@ import (
 func CreatePlaylist(ctx context.Context, user model.User, ms int) (*spotify.FullPlaylist, error) {
 	client := NewClientWithUser(ctx, user)
 
-	playlist, err := client.CreatePlaylistForUser(ctx, user.Id, strconv.Itoa(ms/commontrack.MillisecondsPerMinute)+"min", "", true, false)
+	playlist, err := client.CreatePlaylistForUser(ctx, user.Id, commontrack.FormatDuration(ms), "", true, false)
 	if err != nil {
 		return playlist, WrapSpotifyError(err, model.ErrPlaylistCreationFailed)
 	}
@@ -0,0 +1,51 @@
+package spotify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/zmb3/spotify/v2"
+)
+
+// GetTracks retrieves tracks by URI. Tracks that do not exist are omitted from the result.
+func GetTracks(ctx context.Context, user model.User, uris []string) ([]model.Track, error) {
+	client := NewClientWithUser(ctx, user)
+
+	var tracks []model.Track
+	// Spotify APIは1リクエストあたり50曲まで
+	for start := 0; start < len(uris); start += 50 {
+		end := start + 50
+		if end > len(uris) {
+			end = len(uris)
+		}
+
+		var ids []spotify.ID
+		for _, uri := range uris[start:end] {
+			ids = append(ids, spotify.ID(strings.Replace(uri, "spotify:track:", "", 1)))
+		}
+
+		fullTracks, err := client.GetTracks(ctx, ids)
+		if err != nil {
+			return nil, WrapSpotifyError(err)
+		}
+
+		for _, t := range fullTracks {
+			if t == nil {
+				continue
+			}
+			artistsId := make([]string, len(t.Artists))
+			for i, artist := range t.Artists {
+				artistsId[i] = artist.ID.String()
+			}
+			tracks = append(tracks, model.Track{
+				Uri:        string(t.URI),
+				Isrc:       t.ExternalIDs["isrc"],
+				DurationMs: int(t.Duration),
+				ArtistsId:  artistsId,
+			})
+		}
+	}
+
+	return tracks, nil
+}
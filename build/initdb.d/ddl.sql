@@ -35,10 +35,14 @@ CREATE TABLE spotify_tracks (
 
 DROP TABLE IF EXISTS spotify_playlists CASCADE;
 
+-- tracks: 生成時に選曲した曲（最近使った曲を避けるために参照する）
 CREATE TABLE spotify_playlists (
     "id" VARCHAR(255) PRIMARY KEY,
     INDEX id_index (id),
     "user_id" VARCHAR(255),
+    "tracks" JSONB,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_spotify_playlists_user_created_at (user_id, created_at DESC),
     CONSTRAINT fk_spotify_playlist_user FOREIGN KEY (user_id) REFERENCES spotify_users(id)
 );
 
@@ -102,10 +106,14 @@ CREATE TABLE soundcloud_favorite_tracks (
 
 DROP TABLE IF EXISTS soundcloud_playlists CASCADE;
 
+-- tracks: 生成時に選曲した曲（最近使った曲を避けるために参照する）
 CREATE TABLE soundcloud_playlists (
     "id" VARCHAR(255) PRIMARY KEY,
     INDEX id_index (id),
     "user_id" VARCHAR(255),
+    "tracks" JSONB,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_soundcloud_playlists_user_created_at (user_id, created_at DESC),
     CONSTRAINT fk_soundcloud_playlist_user FOREIGN KEY (user_id) REFERENCES soundcloud_users(id)
 );
 
@@ -129,4 +137,49 @@ CREATE TABLE soundcloud_jwt_refresh_tokens (
     INDEX idx_soundcloud_jwt_user_id (user_id),
     INDEX idx_soundcloud_jwt_expires_at (expires_at),
     CONSTRAINT fk_soundcloud_jwt_refresh_token_user FOREIGN KEY (user_id) REFERENCES soundcloud_users(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+-- Shared Tables
+
+DROP TABLE IF EXISTS user_blocklist CASCADE;
+
+-- ユーザーごとの選曲除外リスト
+-- - service: "spotify" / "soundcloud"
+-- - item_type: "track"（Spotifyはトラック URI、SoundCloudはトラックID）/ "artist"
+CREATE TABLE user_blocklist (
+    "service" VARCHAR(32) NOT NULL,
+    "user_id" VARCHAR(255) NOT NULL,
+    "item_type" VARCHAR(16) NOT NULL,
+    "item_id" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service, user_id, item_type, item_id)
+);
+
+DROP TABLE IF EXISTS playlist_previews CASCADE;
+
+-- プレイリストのプレビュー（作成前の選曲結果）
+-- - commit で同じ曲のプレイリストを作成するまで保持する
+-- - Row-Level TTL: expires_at を過ぎた行を自動削除（15分ごとに実行）
+CREATE TABLE playlist_previews (
+    "token" VARCHAR(64) PRIMARY KEY,
+    "service" VARCHAR(32) NOT NULL,
+    "user_id" VARCHAR(255) NOT NULL,
+    "target_ms" INT NOT NULL,
+    "seed" INT8 NOT NULL,
+    "tracks" JSONB NOT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMP NOT NULL
+) WITH (
+    ttl_expiration_expression = 'expires_at::TIMESTAMPTZ',
+    ttl_job_cron = '*/15 * * * *'
+);
+
+DROP TABLE IF EXISTS catalog_objects CASCADE;
+
+-- 複数のインスタンスで共有するカタログのファイル（CATALOG_STORE=postgres の場合に使う）
+-- - key: "versions/<バージョン>/<ファイル名>" または "current" / "previous"（バージョン名を保存する）
+CREATE TABLE catalog_objects (
+    "key" VARCHAR(512) PRIMARY KEY,
+    "body" BYTES NOT NULL,
+    "updated_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SaveCatalogObject はカタログのファイルを保存する（既にあれば上書きする）
+func SaveCatalogObject(ctx context.Context, db *sql.DB, key string, body []byte) error {
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO catalog_objects (key, body, updated_at)
+        VALUES ($1, $2, CURRENT_TIMESTAMP)
+        ON CONFLICT (key) DO UPDATE SET
+            body = EXCLUDED.body,
+            updated_at = CURRENT_TIMESTAMP`,
+		key, body)
+	return err
+}
+
+// GetCatalogObject はカタログのファイルを取得する。見つからない場合は sql.ErrNoRows を返す。
+func GetCatalogObject(ctx context.Context, db *sql.DB, key string) ([]byte, error) {
+	var body []byte
+	err := db.QueryRowContext(ctx, `SELECT body FROM catalog_objects WHERE key = $1`, key).Scan(&body)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// DeleteCatalogObject はカタログのファイルを削除する
+func DeleteCatalogObject(ctx context.Context, db *sql.DB, key string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM catalog_objects WHERE key = $1`, key)
+	return err
+}
+
+// GetCatalogObjectKeys は prefix で始まるカタログのファイルのキーを取得する
+func GetCatalogObjectKeys(ctx context.Context, db *sql.DB, prefix string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT key FROM catalog_objects WHERE starts_with(key, $1) ORDER BY key`, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -12,16 +12,24 @@ import (
 
 var (
 	dbInstance *sql.DB
-	once       sync.Once
-	initError  error
+	mu         sync.Mutex
 )
 
 // GetDatabaseInstance シングルトンパターンでデータベース接続を提供
+// 接続に失敗した場合は保持せず、次の呼び出しで接続し直す
 func GetDatabaseInstance(db Database) (*sql.DB, error) {
-	once.Do(func() {
-		dbInstance, initError = db.Connect()
-	})
-	return dbInstance, initError
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dbInstance != nil {
+		return dbInstance, nil
+	}
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+	dbInstance = conn
+	return dbInstance, nil
 }
 
 type CockroachDB struct{}
@@ -45,6 +53,7 @@ func (db CockroachDB) Connect() (*sql.DB, error) {
 	dbConn.SetMaxIdleConns(10)
 
 	if err := dbConn.Ping(); err != nil {
+		dbConn.Close()
 		return nil, err
 	}
 	return dbConn, nil
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// SavePlaylistPreview はプレイリストのプレビューを保存する
+func SavePlaylistPreview(db *sql.DB, preview *model.PlaylistPreview) error {
+	tracksJSON, err := json.Marshal(preview.Tracks)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        INSERT INTO playlist_previews (token, service, user_id, target_ms, seed, tracks, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, $7)`,
+		preview.Token, preview.Service, preview.UserID, preview.TargetMs, preview.Seed, tracksJSON, preview.ExpiresAt)
+	return err
+}
+
+// ClaimPlaylistPreview は有効期限内のプレビューを取り出し、同時に削除する。
+// 取り出しと削除を1つの文で行うため、同じプレビューを同時に取り出しても1回しか返さない。
+// 見つからない場合、または他のユーザーのプレビューの場合は model.ErrPreviewNotFound を返す。
+func ClaimPlaylistPreview(db *sql.DB, token, service, userId string) (*model.PlaylistPreview, error) {
+	var preview model.PlaylistPreview
+	var tracksJSON []byte
+	err := db.QueryRow(`
+        DELETE FROM playlist_previews
+        WHERE token = $1 AND service = $2 AND user_id = $3 AND expires_at > CURRENT_TIMESTAMP
+        RETURNING token, service, user_id, target_ms, seed, tracks, expires_at`,
+		token, service, userId).Scan(
+		&preview.Token, &preview.Service, &preview.UserID, &preview.TargetMs, &preview.Seed, &tracksJSON, &preview.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrPreviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(tracksJSON, &preview.Tracks); err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
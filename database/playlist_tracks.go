@@ -0,0 +1,44 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// getRecentPlaylistTracks は spotify_playlists / soundcloud_playlists から
+// ユーザーの最近のプレイリストの曲を新しい順に取得する
+func getRecentPlaylistTracks(db *sql.DB, table string, userId string, limit int, days int) ([][]model.Track, error) {
+	query := fmt.Sprintf(`
+        SELECT tracks FROM %s
+        WHERE user_id = $1 AND tracks IS NOT NULL
+          AND ($2 = 0 OR created_at > CURRENT_TIMESTAMP - $2 * INTERVAL '1 day')
+        ORDER BY created_at DESC`, table)
+	args := []interface{}{userId, days}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var playlists [][]model.Track
+	for rows.Next() {
+		var tracksJSON []byte
+		if err := rows.Scan(&tracksJSON); err != nil {
+			return nil, err
+		}
+		var tracks []model.Track
+		if err := json.Unmarshal(tracksJSON, &tracks); err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, tracks)
+	}
+	return playlists, rows.Err()
+}
@@ -75,7 +75,7 @@ func GetSoundCloudTracksByArtistIds(db *sql.DB, artistIDs []string) ([]model.Tra
 	}
 
 	query := fmt.Sprintf(`
-        SELECT tracks FROM soundcloud_artists WHERE id IN (%s)`,
+        SELECT tracks FROM soundcloud_artists WHERE id IN (%s) ORDER BY id`,
 		strings.Join(placeholders, ","))
 
 	rows, err := db.Query(query, convertToInterfaceSlice(artistIDs)...)
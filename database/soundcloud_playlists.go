@@ -2,18 +2,33 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+
+	"github.com/pp-develop/music-timer-api/model"
 )
 
-func SaveSoundCloudPlaylist(db *sql.DB, playlistId, userId string) error {
-	_, err := db.Exec(`
-        INSERT INTO soundcloud_playlists (id, user_id)
-        VALUES ($1, $2)
+func SaveSoundCloudPlaylist(db *sql.DB, playlistId, userId string, tracks []model.Track) error {
+	tracksJSON, err := json.Marshal(tracks)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        INSERT INTO soundcloud_playlists (id, user_id, tracks, created_at)
+        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
         ON CONFLICT (id) DO UPDATE SET
-            user_id = EXCLUDED.user_id`,
-		playlistId, userId)
+            user_id = EXCLUDED.user_id,
+            tracks = EXCLUDED.tracks`,
+		playlistId, userId, tracksJSON)
 	return err
 }
 
+// GetRecentSoundCloudPlaylistTracks returns the tracks of the user's recent playlists, newest first.
+// limit is the number of playlists and days the age limit (0 means unlimited).
+func GetRecentSoundCloudPlaylistTracks(db *sql.DB, userId string, limit int, days int) ([][]model.Track, error) {
+	return getRecentPlaylistTracks(db, "soundcloud_playlists", userId, limit, days)
+}
+
 func GetSoundCloudPlaylists(db *sql.DB, userId string) ([]string, error) {
 	rows, err := db.Query(`
         SELECT id FROM soundcloud_playlists WHERE user_id = $1`, userId)
@@ -28,7 +28,8 @@ func GetTracksByArtistIds(db *sql.DB, artistIDs []string) ([]model.Track, error)
 	query := fmt.Sprintf(`
         SELECT tracks
         FROM spotify_artists
-        WHERE id IN (%s)`, strings.Join(placeholders, ","))
+        WHERE id IN (%s)
+        ORDER BY id`, strings.Join(placeholders, ","))
 
 	rows, err := db.Query(query, convertToInterfaceSlice(artistIDs)...)
 	if err != nil {
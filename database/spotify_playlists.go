@@ -2,22 +2,35 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"github.com/pp-develop/music-timer-api/model"
 	"github.com/zmb3/spotify/v2"
 )
 
-func SavePlaylist(db *sql.DB, playlist *spotify.FullPlaylist, userId string) error {
-	_, err := db.Exec(`
-        INSERT INTO spotify_playlists (id, user_id)
-        VALUES ($1, $2)
-        ON CONFLICT (id) DO NOTHING`, string(playlist.ID), userId)
+// SavePlaylist は生成したプレイリストと、選曲した曲を保存する
+func SavePlaylist(db *sql.DB, playlist *spotify.FullPlaylist, userId string, tracks []model.Track) error {
+	tracksJSON, err := json.Marshal(tracks)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        INSERT INTO spotify_playlists (id, user_id, tracks, created_at)
+        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+        ON CONFLICT (id) DO NOTHING`, string(playlist.ID), userId, tracksJSON)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// GetRecentPlaylistTracks はユーザーが最近生成したプレイリストの曲を、新しいプレイリストから順に返す。
+// limit は遡るプレイリスト数、days は遡る日数（どちらも0の場合は制限なし）。
+func GetRecentPlaylistTracks(db *sql.DB, userId string, limit int, days int) ([][]model.Track, error) {
+	return getRecentPlaylistTracks(db, "spotify_playlists", userId, limit, days)
+}
+
 func GetAllPlaylists(db *sql.DB, userId string) ([]model.Playlist, error) {
 	var playlists []model.Playlist
 	rows, err := db.Query("SELECT id FROM spotify_playlists WHERE user_id = $1", userId)
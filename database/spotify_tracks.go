@@ -38,21 +38,21 @@ func SaveTracksBatch(db *sql.DB, tracks []spotify.FullTrack) error {
 	return err
 }
 
-// ページ番号とページサイズに基づいてトラックを取得する関数
-func GetTracks(db *sql.DB, pageNumber, pageSize int) ([]model.Track, error) {
-	// OFFSETを計算してLIMIT句を生成
-	offset := (pageNumber - 1) * pageSize
-	limit := pageSize
-
-	// クエリ実行
-	query := "SELECT uri, duration_ms, isrc FROM spotify_tracks LIMIT $1 OFFSET $2"
-	rows, err := db.Query(query, limit, offset)
+// GetTracksAfter は uri の昇順で afterUri より後のトラックを最大 limit 件取得する（キーセットページネーション）。
+// 主キーの uri で範囲を指定するため、何ページ目でも読み飛ばす行がなく、取得にかかる時間は一定になる。
+func GetTracksAfter(db *sql.DB, afterUri string, limit int) ([]model.Track, error) {
+	rows, err := db.Query(`
+        SELECT uri, duration_ms, isrc FROM spotify_tracks
+        WHERE uri > $1
+        ORDER BY uri
+        LIMIT $2`,
+		afterUri, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	tracks := make([]model.Track, 0)
+	tracks := make([]model.Track, 0, limit)
 	for rows.Next() {
 		var track model.Track
 		if err := rows.Scan(&track.Uri, &track.DurationMs, &track.Isrc); err != nil {
@@ -66,29 +66,40 @@ func GetTracks(db *sql.DB, pageNumber, pageSize int) ([]model.Track, error) {
 	return tracks, nil
 }
 
-func GetAllTracks(db *sql.DB) ([]model.Track, error) {
-	var AllTracks []model.Track
-
-	// ページネーションでトラックデータを取得
-	pageSize := 50000
-	pageNumber := 1
+// ExportTracks はすべてのトラックを uri の昇順に pageSize 件ずつ fn に渡す。
+// 各ページは直前のページの最後の uri より後から取得するため、ページ同士が重複することも、
+// 取得中に存在し続けた行が漏れることもない。fn がエラーを返した場合はそこで中断する。
+func ExportTracks(db *sql.DB, pageSize int, fn func(page []model.Track) error) error {
+	after := ""
 	for {
-		// ページ番号とページサイズに基づいてトラックを取得
-		tracks, err := GetTracks(db, pageNumber, pageSize)
+		tracks, err := GetTracksAfter(db, after, pageSize)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		// 取得したトラックデータを処理
-		AllTracks = append(AllTracks, tracks...)
-
-		// ページネーション処理のために、ページ番号をインクリメントして次のページのトラックデータを取得
 		if len(tracks) == 0 {
-			break
+			return nil // データ終了
+		}
+
+		after = tracks[len(tracks)-1].Uri
+		if err := fn(tracks); err != nil {
+			return err
 		}
+		if len(tracks) < pageSize {
+			return nil
+		}
+	}
+}
+
+func GetAllTracks(db *sql.DB) ([]model.Track, error) {
+	var AllTracks []model.Track
 
-		// ページ番号をインクリメント
-		pageNumber++
+	// ページネーションでトラックデータを取得
+	err := ExportTracks(db, 50000, func(tracks []model.Track) error {
+		AllTracks = append(AllTracks, tracks...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return AllTracks, nil
 }
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// spotify_tracks のキーセットページネーションのテスト
+// =============================================================================
+// GetTracksAfter と ExportTracks が以下を満たすことをテストする:
+// 1. ページの境界をまたいでも、すべての曲を uri の昇順に重複なく漏れなく取得する
+// 2. 曲数がページの大きさの倍数でも、空のページを渡さない
+// 3. 取得中に行が追加されても、取得済みのページと重複せず、既存の行が漏れない
+// 4. fn がエラーを返した場合はそこで中断する
+// =============================================================================
+
+// trackTable は spotify_tracks テーブルを模した、テスト用の database/sql ドライバ。
+// GetTracksAfter の SELECT だけを扱う。
+type trackTable struct {
+	mu      sync.Mutex
+	rows    []model.Track // uri の昇順
+	queries int
+}
+
+func newTrackTestDB(t *testing.T, tracks []model.Track) (*sql.DB, *trackTable) {
+	table := &trackTable{}
+	for _, track := range tracks {
+		table.insert(track)
+	}
+	db := sql.OpenDB(table)
+	t.Cleanup(func() { db.Close() })
+	return db, table
+}
+
+func (tt *trackTable) insert(track model.Track) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	i := sort.Search(len(tt.rows), func(i int) bool { return tt.rows[i].Uri >= track.Uri })
+	tt.rows = append(tt.rows, model.Track{})
+	copy(tt.rows[i+1:], tt.rows[i:])
+	tt.rows[i] = track
+}
+
+func (tt *trackTable) Connect(context.Context) (driver.Conn, error) { return &trackConn{tt}, nil }
+func (tt *trackTable) Driver() driver.Driver                        { return nil }
+
+type trackConn struct{ table *trackTable }
+
+func (c *trackConn) Prepare(query string) (driver.Stmt, error) {
+	return &trackStmt{table: c.table, query: strings.TrimSpace(query)}, nil
+}
+func (c *trackConn) Close() error { return nil }
+func (c *trackConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type trackStmt struct {
+	table *trackTable
+	query string
+}
+
+func (s *trackStmt) Close() error  { return nil }
+func (s *trackStmt) NumInput() int { return -1 }
+
+func (s *trackStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("unexpected exec: " + s.query)
+}
+
+// Query は WHERE uri > $1 ORDER BY uri LIMIT $2 を行う
+func (s *trackStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT uri, duration_ms, isrc FROM spotify_tracks") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+	s.table.queries++
+
+	after, limit := args[0].(string), int(args[1].(int64))
+	rows := &trackRows{}
+	for _, track := range s.table.rows {
+		if len(rows.rows) == limit {
+			break
+		}
+		if track.Uri > after {
+			rows.rows = append(rows.rows, []driver.Value{track.Uri, int64(track.DurationMs), track.Isrc})
+		}
+	}
+	return rows, nil
+}
+
+type trackRows struct {
+	rows [][]driver.Value
+}
+
+func (r *trackRows) Columns() []string { return []string{"uri", "duration_ms", "isrc"} }
+func (r *trackRows) Close() error      { return nil }
+func (r *trackRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// numberedTracks は uri が track00, track01, ... の n 曲を返すテスト用ヘルパー
+func numberedTracks(n int) []model.Track {
+	tracks := make([]model.Track, n)
+	for i := range tracks {
+		tracks[i] = model.Track{Uri: fmt.Sprintf("track%02d", i), DurationMs: 180000 + i, Isrc: "JPABC2400001"}
+	}
+	return tracks
+}
+
+// exportAll は ExportTracks で取得したページを返すテスト用ヘルパー
+func exportAll(t *testing.T, db *sql.DB, pageSize int, onPage func(page []model.Track)) [][]model.Track {
+	t.Helper()
+	var pages [][]model.Track
+	err := ExportTracks(db, pageSize, func(page []model.Track) error {
+		pages = append(pages, page)
+		if onPage != nil {
+			onPage(page)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportTracks() unexpected error: %v", err)
+	}
+	return pages
+}
+
+// assertUris は pages を連結した uri が want と一致することを確かめるテスト用ヘルパー
+func assertUris(t *testing.T, pages [][]model.Track, want []string) {
+	t.Helper()
+	var got []string
+	for _, page := range pages {
+		for _, track := range page {
+			got = append(got, track.Uri)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestGetTracksAfter は、指定した uri より後の曲を昇順に取得することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 5曲
+//   - 期待結果: 先頭から2曲、track01 の後から2曲、最後の曲の後は0曲。曲の内容も一致する
+func TestGetTracksAfter(t *testing.T) {
+	tracks := numberedTracks(5)
+	db, _ := newTrackTestDB(t, tracks)
+
+	tests := []struct {
+		after string
+		want  []model.Track
+	}{
+		{"", tracks[0:2]},
+		{"track01", tracks[2:4]},
+		{"track04", nil},
+	}
+	for _, tt := range tests {
+		got, err := GetTracksAfter(db, tt.after, 2)
+		if err != nil {
+			t.Fatalf("GetTracksAfter(%q) unexpected error: %v", tt.after, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("GetTracksAfter(%q): expected %d tracks, got %d", tt.after, len(tt.want), len(got))
+		}
+		for i := range got {
+			if got[i].Uri != tt.want[i].Uri || got[i].DurationMs != tt.want[i].DurationMs || got[i].Isrc != tt.want[i].Isrc {
+				t.Errorf("GetTracksAfter(%q)[%d]: expected %+v, got %+v", tt.after, i, tt.want[i], got[i])
+			}
+		}
+	}
+}
+
+// TestExportTracks_PageBoundary は、ページの境界をまたいで重複も漏れもなく取得することをテストする。
+//
+// テストシナリオ:
+//   - 7曲を3件ずつ → 3, 3, 1 件の3ページ、3回のクエリ
+//   - 6曲を3件ずつ → 3, 3 件の2ページ（最後に空のページを渡さない）
+func TestExportTracks_PageBoundary(t *testing.T) {
+	tests := []struct {
+		name        string
+		tracks      int
+		pageSizes   []int
+		wantQueries int
+	}{
+		{"last page is partial", 7, []int{3, 3, 1}, 3},
+		{"exact multiple of page size", 6, []int{3, 3}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks := numberedTracks(tt.tracks)
+			db, table := newTrackTestDB(t, tracks)
+
+			pages := exportAll(t, db, 3, nil)
+
+			if len(pages) != len(tt.pageSizes) {
+				t.Fatalf("Expected %d pages, got %d", len(tt.pageSizes), len(pages))
+			}
+			for i, page := range pages {
+				if len(page) != tt.pageSizes[i] {
+					t.Errorf("Page %d: expected %d tracks, got %d", i, tt.pageSizes[i], len(page))
+				}
+			}
+			var want []string
+			for _, track := range tracks {
+				want = append(want, track.Uri)
+			}
+			assertUris(t, pages, want)
+			if table.queries != tt.wantQueries {
+				t.Errorf("Expected %d queries, got %d", tt.wantQueries, table.queries)
+			}
+		})
+	}
+}
+
+// TestExportTracks_ConcurrentInsert は、取得中に行が追加されてもページがずれないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 6曲を2件ずつ取得し、1ページ目を受け取った後に
+//     取得済みの範囲（track00a）と未取得の範囲（track04a）に1曲ずつ追加する
+//   - 期待結果: 既存の6曲と track04a が1回ずつ、uri の昇順に取得される（track00a は取得済みの範囲なので含まれない）
+func TestExportTracks_ConcurrentInsert(t *testing.T) {
+	db, table := newTrackTestDB(t, numberedTracks(6))
+
+	inserted := false
+	pages := exportAll(t, db, 2, func([]model.Track) {
+		if inserted {
+			return
+		}
+		inserted = true
+		table.insert(model.Track{Uri: "track00a", DurationMs: 200000})
+		table.insert(model.Track{Uri: "track04a", DurationMs: 200000})
+	})
+
+	assertUris(t, pages, []string{"track00", "track01", "track02", "track03", "track04", "track04a", "track05"})
+}
+
+// TestExportTracks_Error は、fn がエラーを返した場合に中断することをテストする。
+func TestExportTracks_Error(t *testing.T) {
+	db, table := newTrackTestDB(t, numberedTracks(6))
+	errStop := errors.New("stop")
+
+	calls := 0
+	err := ExportTracks(db, 2, func([]model.Track) error {
+		calls++
+		return errStop
+	})
+
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected fn error, got %v", err)
+	}
+	if calls != 1 || table.queries != 1 {
+		t.Errorf("Expected 1 call and 1 query, got %d calls and %d queries", calls, table.queries)
+	}
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// GetBlocklist はユーザーの除外リストを取得する
+func GetBlocklist(db *sql.DB, service, userId string) ([]model.BlocklistItem, error) {
+	rows, err := db.Query(`
+        SELECT item_type, item_id, created_at FROM user_blocklist
+        WHERE service = $1 AND user_id = $2
+        ORDER BY created_at, item_type, item_id`, service, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.BlocklistItem{}
+	for rows.Next() {
+		var item model.BlocklistItem
+		if err := rows.Scan(&item.Type, &item.ID, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddBlocklistItems はユーザーの除外リストに項目を追加する（登録済みの項目は無視する）
+func AddBlocklistItems(db *sql.DB, service, userId string, items []model.BlocklistItem) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		_, err := tx.Exec(`
+            INSERT INTO user_blocklist (service, user_id, item_type, item_id, created_at)
+            VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+            ON CONFLICT (service, user_id, item_type, item_id) DO NOTHING`,
+			service, userId, item.Type, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteBlocklistItem はユーザーの除外リストから項目を削除する
+func DeleteBlocklistItem(db *sql.DB, service, userId, itemType, itemId string) error {
+	_, err := db.Exec(`
+        DELETE FROM user_blocklist
+        WHERE service = $1 AND user_id = $2 AND item_type = $3 AND item_id = $4`,
+		service, userId, itemType, itemId)
+	return err
+}
@@ -1,11 +1,29 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+
+	"github.com/pp-develop/music-timer-api/database"
 	_ "github.com/pp-develop/music-timer-api/pkg/logger" // JSON形式のslogロガーを初期化
 	"github.com/pp-develop/music-timer-api/router"
+	"github.com/pp-develop/music-timer-api/spotify/json"
 )
 
 func main() {
 	router := router.Create()
+
+	// カタログの読み込みと定期的な作成し直しをバックグラウンドで開始する
+	schedule, err := json.RefreshSchedule()
+	if err != nil {
+		slog.Error("invalid CATALOG_REFRESH_CRON", slog.Any("error", err))
+		os.Exit(1)
+	}
+	json.StartRefresh(context.Background(), func() (*sql.DB, error) {
+		return database.GetDatabaseInstance(database.CockroachDB{})
+	}, schedule)
+
 	router.Run(":8080")
 }
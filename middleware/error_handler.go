@@ -32,79 +32,91 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 func handleError(c *gin.Context, err error) {
 	slog.Error("handling error", slog.Any("error", err), slog.String("type", fmt.Sprintf("%T", err)))
 
+	// エラーに付けられた追加情報はレスポンスの details に出す
+	details := model.ErrorDetails(err)
+
 	// 認証エラー
 	if errors.Is(err, model.ErrAccessTokenExpired) {
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
-			Code: model.CodeTokenExpired,
-		})
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse(model.CodeTokenExpired, details))
+		return
+	}
+
+	// リクエストエラー
+	if errors.Is(err, model.ErrInvalidRequest) {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidRequest, details))
+		return
+	}
+	if errors.Is(err, model.ErrInvalidDuration) {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidDuration, details))
+		return
+	}
+	if errors.Is(err, model.ErrInvalidSegment) {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidSegment, details))
+		return
+	}
+	if errors.Is(err, model.ErrInvalidPinnedTracks) {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidPinned, details))
 		return
 	}
 
 	// リソース不足エラー
 	if errors.Is(err, model.ErrNotFoundTracks) {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{
-			Code: model.CodeTracksNotFound,
-		})
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodeTracksNotFound, details))
+		return
+	}
+
+	if errors.Is(err, model.ErrPreviewNotFound) {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodePreviewNotFound, details))
 		return
 	}
 
 	if errors.Is(err, model.ErrNotEnoughTracks) {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{
-			Code: model.CodeTimeoutInsufficientTracks,
-		})
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodeTimeoutInsufficientTracks, details))
 		return
 	}
 
 	if errors.Is(err, model.ErrTimeoutCreatePlaylist) {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{
-			Code: model.CodeTimeoutNoMatch,
-		})
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodeTimeoutNoMatch, details))
 		return
 	}
 
 	if errors.Is(err, model.ErrNoFavoriteTracks) {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{
-			Code: model.CodeNoFavoriteTracks,
-		})
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodeNoFavoriteTracks, details))
+		return
+	}
+
+	// カタログの準備中（/api/ready と同じく 503 を返す）
+	if errors.Is(err, model.ErrCatalogNotReady) {
+		c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse(model.CodeCatalogNotReady, details))
 		return
 	}
 
 	// Spotify API制限エラー
 	if errors.Is(err, model.ErrSpotifyRateLimit) {
-		c.JSON(http.StatusTooManyRequests, model.ErrorResponse{
-			Code: model.CodeSpotifyRateLimit,
-		})
+		c.JSON(http.StatusTooManyRequests, model.NewErrorResponse(model.CodeSpotifyRateLimit, details))
 		return
 	}
 
 	if errors.Is(err, model.ErrPlaylistQuotaExceeded) {
-		c.JSON(http.StatusTooManyRequests, model.ErrorResponse{
-			Code: model.CodePlaylistQuotaExceeded,
-		})
+		c.JSON(http.StatusTooManyRequests, model.NewErrorResponse(model.CodePlaylistQuotaExceeded, details))
 		return
 	}
 
 	// 処理エラー
 	if errors.Is(err, model.ErrPlaylistCreationFailed) {
-		c.JSON(http.StatusBadGateway, model.ErrorResponse{
-			Code: model.CodePlaylistCreationFailed,
-		})
+		c.JSON(http.StatusBadGateway, model.NewErrorResponse(model.CodePlaylistCreationFailed, details))
 		return
 	}
 
 	if errors.Is(err, model.ErrTrackAdditionFailed) {
-		c.JSON(http.StatusBadGateway, model.ErrorResponse{
-			Code: model.CodePlaylistCreationFailed,
-		})
+		c.JSON(http.StatusBadGateway, model.NewErrorResponse(model.CodePlaylistCreationFailed, details))
 		return
 	}
 
 	// セッション/認証エラー
 	// JWT/セッション認証を問わず、401 Unauthorized で統一
 	if errors.Is(err, model.ErrFailedGetSession) {
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
-			Code: model.CodeTokenExpired,
-		})
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse(model.CodeTokenExpired, details))
 		return
 	}
 
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// 除外リストの項目の種類
+const (
+	BlocklistTypeTrack  = "track"
+	BlocklistTypeArtist = "artist"
+)
+
+// BlocklistItem はユーザーが選曲から除外した曲またはアーティスト
+type BlocklistItem struct {
+	Type      string    `json:"type" binding:"required,oneof=track artist"`
+	ID        string    `json:"id" binding:"required,max=255"`
+	CreatedAt time.Time `json:"created_at"`
+}
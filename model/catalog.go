@@ -0,0 +1,18 @@
+package model
+
+// CatalogCoverageResponse はカタログのマーケット別の曲数と再生時間
+type CatalogCoverageResponse struct {
+	Version      string `json:"version"`       // カタログのバージョン
+	TotalTracks  int    `json:"total_tracks"`  // すべての曲数
+	TotalMinutes int    `json:"total_minutes"` // すべての曲の再生時間（分）
+	// UnknownMarketTracks はISRCから国コードが分からない曲数（マーケットを指定した選曲では使われない）
+	UnknownMarketTracks int              `json:"unknown_market_tracks"`
+	Markets             []MarketCoverage `json:"markets"` // 曲数の多い順
+}
+
+// MarketCoverage は1つのマーケット（ISRCの国コード）の曲数と再生時間
+type MarketCoverage struct {
+	Market  string `json:"market"`
+	Tracks  int    `json:"tracks"`
+	Minutes int    `json:"minutes"`
+}
@@ -0,0 +1,24 @@
+package model
+
+// SelectionDiagnostics は選曲処理の診断情報。
+// debug 指定時は作成APIのレスポンスと、選曲エラーの details に含める。
+type SelectionDiagnostics struct {
+	PoolSize          int              `json:"pool_size"`            // 候補プールの曲数
+	FilteredOut       int              `json:"filtered_out"`         // 曲の長さの範囲外として除外した曲数
+	Blocked           int              `json:"blocked"`              // 除外リストにより除外した曲数
+	RecentExcluded    int              `json:"recent_excluded"`      // 最近使った曲として避けた曲数
+	GapFilled         int              `json:"gap_filled"`           // 隙間を埋めるために GapFill から選んだ曲数
+	PoolDurationMs    int              `json:"pool_duration_ms"`     // 候補プールの総再生時間
+	DurationHistogram []DurationBucket `json:"duration_histogram"`   // 候補プールの曲の長さの分布
+	TopFilter         string           `json:"top_filter,omitempty"` // 最も多くの候補を除外した条件（track_length / blocklist / recent）
+	Attempts          int              `json:"attempts"`             // 組み合わせ探索の試行回数
+	ElapsedMs         int64            `json:"elapsed_ms"`           // 選曲にかかった時間
+	RemainderMs       int              `json:"remainder_ms"`         // 指定時間から選んだ曲の合計を引いた残り（再生時間不足の場合は不足分）
+}
+
+// DurationBucket は曲の長さの分布の1区間 [FromMs, ToMs)。ToMs が0の場合は上限なし。
+type DurationBucket struct {
+	FromMs int `json:"from_ms"`
+	ToMs   int `json:"to_ms,omitempty"`
+	Count  int `json:"count"`
+}
@@ -12,11 +12,15 @@ const (
 	CodeNotEnoughTracks      = "NOT_ENOUGH_TRACKS"       // 指定された再生時間に対して十分なトラックが見つからない
 	CodeNoFavoriteTracks     = "NO_FAVORITE_TRACKS"      // ユーザーのお気に入りトラックが存在しない
 	CodeTracksNotFound       = "TRACKS_NOT_FOUND"        // データベースにトラックが存在しない
+	CodePreviewNotFound      = "PREVIEW_NOT_FOUND"       // プレビューが存在しない、または有効期限切れ
 
 	// API制限
 	CodeSpotifyRateLimit      = "SPOTIFY_RATE_LIMIT"      // Spotify APIのレート制限に到達
 	CodePlaylistQuotaExceeded = "PLAYLIST_QUOTA_EXCEEDED" // Spotifyアカウントのプレイリスト作成上限に到達
 
+	// 準備中
+	CodeCatalogNotReady = "CATALOG_NOT_READY" // カタログをまだ読み込んでいない（起動直後など）
+
 	// 認証エラー
 	CodeTokenExpired = "TOKEN_EXPIRED" // アクセストークンの有効期限切れ
 
@@ -24,6 +28,12 @@ const (
 	CodeTimeoutInsufficientTracks = "TIMEOUT_INSUFFICIENT_TRACKS" // タイムアウト：トラックの総再生時間が不足
 	CodeTimeoutNoMatch            = "TIMEOUT_NO_MATCH"            // タイムアウト：トラックは足りているが組み合わせが見つからない
 
+	// リクエストエラー
+	CodeInvalidRequest  = "INVALID_REQUEST"  // リクエストのパラメータが不正
+	CodeInvalidDuration = "INVALID_DURATION" // 再生時間の指定がない、複数指定されている、または範囲外
+	CodeInvalidSegment  = "INVALID_SEGMENT"  // インターバルの区間指定が不正
+	CodeInvalidPinned   = "INVALID_PINNED"   // 固定曲が見つからない、または合計が指定時間を超える
+
 	// 処理エラー
 	CodePlaylistCreationFailed = "PLAYLIST_CREATION_FAILED" // Spotify上でプレイリストの作成に失敗
 	CodeInternalError          = "INTERNAL_ERROR"           // その他の内部エラー
@@ -14,6 +14,11 @@ var (
 	ErrFailedGetDB           = errors.New("Failed to get database instance")
 	ErrInvalidRequest        = errors.New("Invalid request")
 	ErrInvalidRefreshToken   = errors.New("Invalid or expired refresh token")
+	ErrInvalidDuration       = errors.New("Invalid playlist duration")
+	ErrInvalidSegment        = errors.New("Invalid playlist segment")
+	ErrInvalidPinnedTracks   = errors.New("Invalid pinned tracks")
+	ErrPreviewNotFound       = errors.New("preview: Not Found or expired")
+	ErrCatalogNotReady       = errors.New("catalog: Not ready")
 
 	// リソース不足エラー
 	ErrNotEnoughTracks       = errors.New("Not enough tracks for specified duration")
@@ -27,3 +32,32 @@ var (
 	ErrPlaylistCreationFailed = errors.New("Failed to create playlist on Spotify")
 	ErrTrackAdditionFailed    = errors.New("Failed to add tracks to playlist")
 )
+
+// DetailedError はエラーレスポンスの details に出す追加情報を持つエラー。
+// errors.Is では元のエラーとして判定される。
+type DetailedError struct {
+	Err     error
+	Details map[string]interface{}
+}
+
+func (e *DetailedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DetailedError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails はエラーに追加情報を付ける
+func WithDetails(err error, details map[string]interface{}) error {
+	return &DetailedError{Err: err, Details: details}
+}
+
+// ErrorDetails はエラーに付けられた追加情報を返す（なければ nil）
+func ErrorDetails(err error) map[string]interface{} {
+	var detailed *DetailedError
+	if errors.As(err, &detailed) {
+		return detailed.Details
+	}
+	return nil
+}
@@ -3,3 +3,49 @@ package model
 type Playlist struct {
 	ID string `json:"id"`
 }
+
+// CreatePlaylistResponse はプレイリスト作成APIのレスポンス
+type CreatePlaylistResponse struct {
+	PlaylistID  string `json:"playlist_id"`
+	SecretToken string `json:"secret_token,omitempty"` // SoundCloudの非公開プレイリスト用トークン
+	Seed        int64  `json:"seed"`                   // 選曲に使った乱数シード
+	DeltaMs     int    `json:"delta_ms"`               // 合計再生時間と指定時間の差（正: 長い、負: 短い）
+
+	// 許容範囲内の組み合わせがなく、近似で作成した場合の説明（allowApproximate 指定時のみ）
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// 選曲の診断情報（debug 指定時のみ）
+	Diagnostics *SelectionDiagnostics `json:"diagnostics,omitempty"`
+
+	// 出自付きの曲の一覧（fillFromCatalog 指定時のみ）
+	Tracks []Track `json:"tracks,omitempty"`
+
+	// インターバルプレイリストの区間の境界（インターバル作成時のみ）
+	Segments []SegmentBoundary `json:"segments,omitempty"`
+
+	// 選曲元ごとの内訳（複数の選曲元を混ぜた作成時のみ）
+	Shares []MixShare `json:"shares,omitempty"`
+}
+
+// SegmentBoundary はインターバルプレイリストの1区間がプレイリストのどこにあたるかを表す
+type SegmentBoundary struct {
+	Label       string `json:"label"`
+	Source      string `json:"source"`
+	StartMs     int    `json:"start_ms"` // プレイリスト先頭からの開始位置
+	EndMs       int    `json:"end_ms"`   // プレイリスト先頭からの終了位置
+	TrackCount  int    `json:"track_count"`
+	DeltaMs     int    `json:"delta_ms"`              // 区間の再生時間と指定時間の差
+	Approximate bool   `json:"approximate,omitempty"` // 近似で選曲した区間
+
+	// 区間の選曲の診断情報（debug 指定時のみ）
+	Diagnostics *SelectionDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// MixShare は複数の選曲元を混ぜたプレイリストの、選曲元ごとの内訳
+type MixShare struct {
+	Source     string `json:"source"`
+	Weight     int    `json:"weight"`    // 指定された配分（%）
+	TargetMs   int    `json:"target_ms"` // 配分から求めた再生時間
+	ActualMs   int    `json:"actual_ms"` // 実際に選んだ曲の合計再生時間
+	TrackCount int    `json:"track_count"`
+}
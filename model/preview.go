@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// PlaylistPreview は作成前のプレイリストの選曲結果
+type PlaylistPreview struct {
+	Token     string
+	Service   string
+	UserID    string
+	TargetMs  int
+	Seed      int64
+	Tracks    []Track
+	ExpiresAt time.Time
+}
+
+// TotalMs はプレビューした曲の合計再生時間を返す
+func (p *PlaylistPreview) TotalMs() int {
+	total := 0
+	for _, t := range p.Tracks {
+		total += t.DurationMs
+	}
+	return total
+}
+
+// PreviewPlaylistResponse はプレイリストのプレビューAPIのレスポンス
+type PreviewPlaylistResponse struct {
+	PreviewToken string    `json:"preview_token"` // commit に渡すトークン
+	ExpiresAt    time.Time `json:"expires_at"`    // トークンの有効期限
+	Seed         int64     `json:"seed"`
+	TargetMs     int       `json:"target_ms"` // 指定された再生時間
+	TotalMs      int       `json:"total_ms"`  // 選曲した曲の合計再生時間
+	GapMs        int       `json:"gap_ms"`    // 指定時間との差（total_ms - target_ms）
+	Tracks       []Track   `json:"tracks"`
+
+	// 許容範囲内の組み合わせがなく、近似で選曲した場合の説明（allowApproximate 指定時のみ）
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// 選曲の診断情報（debug 指定時のみ）
+	Diagnostics *SelectionDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// PlaylistCandidate はプレイリスト候補APIで返す候補の1つ。
+// preview_token を commit に渡すと、その候補の曲でプレイリストが作成される。
+type PlaylistCandidate struct {
+	PreviewPlaylistResponse
+	Rank            int     `json:"rank"`             // 順位（1始まり）
+	ArtistDiversity float64 `json:"artist_diversity"` // 曲数に対する異なるアーティスト数の割合
+	Freshness       float64 `json:"freshness"`        // 最近のプレイリストで使っていない曲の割合
+}
+
+// PlaylistCandidatesResponse はプレイリスト候補APIのレスポンス
+type PlaylistCandidatesResponse struct {
+	Candidates []PlaylistCandidate `json:"candidates"`
+}
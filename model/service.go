@@ -0,0 +1,7 @@
+package model
+
+// サービス名（JWT の service クレーム、user_blocklist.service などで使う）
+const (
+	ServiceSpotify    = "spotify"
+	ServiceSoundCloud = "soundcloud"
+)
@@ -6,4 +6,12 @@ type Track struct {
 	Isrc       string   `json:"isrc"`
 	ArtistsId  []string `json:"artists_id"`
 	ID         string   `json:"id,omitempty"`
+	// Origin は曲の出自（fillFromCatalog 指定時のみ。favorites / artists / catalog / pinned）
+	Origin string `json:"origin,omitempty"`
 }
+
+// 曲の出自
+const (
+	TrackOriginCatalog = "catalog" // 隙間を埋めるためにグローバルカタログから選んだ曲
+	TrackOriginPinned  = "pinned"  // 固定曲
+)
@@ -0,0 +1,83 @@
+package blocklist
+
+import (
+	"database/sql"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/database"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+// AddRequest は除外リストへの追加リクエスト
+type AddRequest struct {
+	Items []model.BlocklistItem `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+// List はログイン中のユーザーの除外リストを返す
+func List(c *gin.Context, service string) ([]model.BlocklistItem, error) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	return database.GetBlocklist(dbInstance, service, userId)
+}
+
+// Add はログイン中のユーザーの除外リストに項目を追加し、追加後の除外リストを返す
+func Add(c *gin.Context, service string) ([]model.BlocklistItem, error) {
+	var json AddRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		return nil, err
+	}
+
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	if err := database.AddBlocklistItems(dbInstance, service, userId, json.Items); err != nil {
+		return nil, err
+	}
+	return database.GetBlocklist(dbInstance, service, userId)
+}
+
+// Remove はログイン中のユーザーの除外リストから、パスの :type と :id で指定された項目を削除する
+func Remove(c *gin.Context, service string) error {
+	itemType := c.Param("type")
+	if itemType != model.BlocklistTypeTrack && itemType != model.BlocklistTypeArtist {
+		return model.ErrInvalidRequest
+	}
+
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return model.ErrFailedGetDB
+	}
+
+	return database.DeleteBlocklistItem(dbInstance, service, userId, itemType, c.Param("id"))
+}
+
+// Load は選曲で使う除外リストを読み込む
+func Load(db *sql.DB, service, userId string) (*commontrack.Blocklist, error) {
+	items, err := database.GetBlocklist(db, service, userId)
+	if err != nil {
+		return nil, err
+	}
+	return commontrack.NewBlocklist(items), nil
+}
@@ -0,0 +1,105 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// cron 式のテスト
+// =============================================================================
+// Parse と Next が以下を満たすことをテストする:
+// 1. 値・範囲・間隔・カンマ区切りを解析し、不正な式はエラーにする
+// 2. 指定した時刻より後で最初に一致する時刻を返す
+// 3. 日と曜日の両方を指定した場合は、どちらかに一致する日に実行する
+// =============================================================================
+
+func utc(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TestParse_Invalid は不正な cron 式をテストする。
+//
+// テストシナリオ:
+//   - フィールド数の不足、範囲外の値、逆順の範囲、0以下の間隔、数値でない値
+//   - 期待結果: すべてエラー
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 3 * *",
+		"60 3 * * *",
+		"0 24 * * *",
+		"0 3 0 * *",
+		"0 3 * 13 *",
+		"0 3 * * 8",
+		"30-10 * * * *",
+		"*/0 * * * *",
+		"a 3 * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
+
+// TestNext は次の実行時刻をテストする。
+//
+// テストシナリオ:
+//   - 毎日 03:30: 03:29 → 当日 03:30、03:30 → 翌日 03:30（同じ時刻は含まない）
+//   - 15分ごと: 10:07 → 10:15、10:50 → 11:00
+//   - 範囲と間隔: 9-17時の0分と30分 → 17:30 の次は翌日 09:00
+//   - 月末をまたぐ: 毎月1日 00:00、12/15 → 翌年1/1
+//   - 曜日: 毎週日曜（7）03:00、2026-10-14（水）→ 2026-10-18（日）
+//   - 存在しない日付（2月30日） → ゼロ値
+func TestNext(t *testing.T) {
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"30 3 * * *", "2026-10-17 03:29", "2026-10-17 03:30"},
+		{"30 3 * * *", "2026-10-17 03:30", "2026-10-18 03:30"},
+		{"*/15 * * * *", "2026-10-17 10:07", "2026-10-17 10:15"},
+		{"*/15 * * * *", "2026-10-17 10:50", "2026-10-17 11:00"},
+		{"0,30 9-17 * * *", "2026-10-17 17:30", "2026-10-18 09:00"},
+		{"0 0 1 * *", "2026-12-15 12:00", "2027-01-01 00:00"},
+		{"0 3 * * 7", "2026-10-14 12:00", "2026-10-18 03:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := s.Next(utc(tt.from)); !got.Equal(utc(tt.want)) {
+			t.Errorf("%q from %s: expected %s, got %s", tt.expr, tt.from, tt.want, got)
+		}
+	}
+
+	s, _ := Parse("0 0 30 2 *")
+	if got := s.Next(utc("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("Expected zero time for Feb 30, got %s", got)
+	}
+}
+
+// TestNext_DayOfMonthOrWeek は日と曜日の両方を指定した場合をテストする。
+//
+// テストシナリオ:
+//   - 毎月13日または金曜日の 00:00、2026-10-10（土）から
+//   - 期待結果: 10/13（火、日が一致）→ 10/16（金、曜日が一致）
+func TestNext_DayOfMonthOrWeek(t *testing.T) {
+	s, err := Parse("0 0 13 * 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := s.Next(utc("2026-10-10 00:00"))
+	if !first.Equal(utc("2026-10-13 00:00")) {
+		t.Errorf("Expected 2026-10-13, got %s", first)
+	}
+	if second := s.Next(first); !second.Equal(utc("2026-10-16 00:00")) {
+		t.Errorf("Expected 2026-10-16, got %s", second)
+	}
+}
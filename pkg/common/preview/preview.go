@@ -0,0 +1,102 @@
+package preview
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/database"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// TTL はプレビューの有効期限
+const TTL = 30 * time.Minute
+
+// CommitRequest はプレビューした曲でプレイリストを作成するリクエスト
+type CommitRequest struct {
+	PreviewToken string `json:"previewToken" binding:"required,max=64"`
+}
+
+// Save は選曲結果をプレビューとして保存し、レスポンスを返す
+func Save(db *sql.DB, service, userId string, targetMs int, seed int64, result *commontrack.Result) (*model.PreviewPlaylistResponse, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &model.PlaylistPreview{
+		Token:     token,
+		Service:   service,
+		UserID:    userId,
+		TargetMs:  targetMs,
+		Seed:      seed,
+		Tracks:    result.Tracks,
+		ExpiresAt: time.Now().UTC().Add(TTL),
+	}
+	if err := database.SavePlaylistPreview(db, preview); err != nil {
+		return nil, err
+	}
+
+	return &model.PreviewPlaylistResponse{
+		PreviewToken: token,
+		ExpiresAt:    preview.ExpiresAt,
+		Seed:         seed,
+		TargetMs:     targetMs,
+		TotalMs:      result.TotalMs,
+		GapMs:        result.TotalMs - targetMs,
+		Tracks:       result.Tracks,
+		Details:      result.Approximation.Details(),
+	}, nil
+}
+
+// SaveCandidates は順位付けされた候補をそれぞれプレビューとして保存し、レスポンスを返す
+func SaveCandidates(db *sql.DB, service, userId string, targetMs int, candidates []commontrack.Candidate) (*model.PlaylistCandidatesResponse, error) {
+	response := &model.PlaylistCandidatesResponse{
+		Candidates: make([]model.PlaylistCandidate, 0, len(candidates)),
+	}
+	for i, c := range candidates {
+		p, err := Save(db, service, userId, targetMs, c.Seed, &c.Result)
+		if err != nil {
+			return nil, err
+		}
+		response.Candidates = append(response.Candidates, model.PlaylistCandidate{
+			PreviewPlaylistResponse: *p,
+			Rank:                    i + 1,
+			ArtistDiversity:         c.ArtistDiversity,
+			Freshness:               c.Freshness,
+		})
+	}
+	return response, nil
+}
+
+// Commit はプレビューを取り出し、create でプレビューの曲どおりにプレイリストを作成する。
+// プレビューは取り出すと同時に削除するため、同じプレビューから二重に作成されることはない
+// （2回目以降は model.ErrPreviewNotFound を返す）。
+// create に失敗した場合はプレビューを戻し、作成し直せるようにする。
+func Commit(db *sql.DB, token, service, userId string, create func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error)) (*model.CreatePlaylistResponse, error) {
+	p, err := database.ClaimPlaylistPreview(db, token, service, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := create(p)
+	if err != nil {
+		if restoreErr := database.SavePlaylistPreview(db, p); restoreErr != nil {
+			slog.Warn("failed to restore playlist preview", slog.Any("error", restoreErr))
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// newToken は推測できないプレビュートークンを生成する
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
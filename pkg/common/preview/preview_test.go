@@ -0,0 +1,217 @@
+package preview
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// =============================================================================
+// プレビューからのプレイリスト作成のテスト
+// =============================================================================
+// Commit が以下を満たすことをテストする:
+// 1. 同じプレビューからは1回しか作成されない（2回目以降は ErrPreviewNotFound）
+// 2. 同時に同じプレビューを作成しても1回しか作成されない
+// 3. 作成に失敗した場合はプレビューが戻り、作成し直せる
+// =============================================================================
+
+// previewTable は playlist_previews テーブルを模した、テスト用の database/sql ドライバ。
+// INSERT と DELETE ... RETURNING だけを扱う。
+type previewTable struct {
+	mu   sync.Mutex
+	rows map[string][]driver.Value // token → token, service, user_id, target_ms, seed, tracks, expires_at
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	db := sql.OpenDB(&previewTable{rows: make(map[string][]driver.Value)})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (p *previewTable) Connect(context.Context) (driver.Conn, error) { return &previewConn{p}, nil }
+func (p *previewTable) Driver() driver.Driver                        { return nil }
+
+type previewConn struct{ table *previewTable }
+
+func (c *previewConn) Prepare(query string) (driver.Stmt, error) {
+	return &previewStmt{table: c.table, query: strings.TrimSpace(query)}, nil
+}
+func (c *previewConn) Close() error { return nil }
+func (c *previewConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type previewStmt struct {
+	table *previewTable
+	query string
+}
+
+func (s *previewStmt) Close() error  { return nil }
+func (s *previewStmt) NumInput() int { return -1 }
+
+func (s *previewStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !strings.HasPrefix(s.query, "INSERT INTO playlist_previews") {
+		return nil, errors.New("unexpected exec: " + s.query)
+	}
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+	s.table.rows[args[0].(string)] = args
+	return driver.RowsAffected(1), nil
+}
+
+func (s *previewStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "DELETE FROM playlist_previews") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+
+	rows := &previewRows{}
+	row, ok := s.table.rows[args[0].(string)]
+	if ok && row[1] == args[1] && row[2] == args[2] && row[6].(time.Time).After(time.Now()) {
+		delete(s.table.rows, args[0].(string))
+		rows.rows = append(rows.rows, row)
+	}
+	return rows, nil
+}
+
+type previewRows struct {
+	rows [][]driver.Value
+}
+
+func (r *previewRows) Columns() []string {
+	return []string{"token", "service", "user_id", "target_ms", "seed", "tracks", "expires_at"}
+}
+func (r *previewRows) Close() error { return nil }
+func (r *previewRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// savePreview は2曲のプレビューを保存してトークンを返すテスト用ヘルパー
+func savePreview(t *testing.T, db *sql.DB) string {
+	result := &commontrack.Result{
+		Tracks:  []model.Track{{Uri: "track1", DurationMs: 180000}, {Uri: "track2", DurationMs: 120000}},
+		TotalMs: 300000,
+	}
+	response, err := Save(db, model.ServiceSpotify, "user1", 300000, 42, result)
+	if err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	return response.PreviewToken
+}
+
+// TestCommit_Twice は、同じプレビューから2回作成できないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 保存したプレビューのトークンで2回 Commit する
+//   - 期待結果: 1回目は成功（プレビューの曲とシードで作成）、2回目は ErrPreviewNotFound で作成しない
+func TestCommit_Twice(t *testing.T) {
+	db := newTestDB(t)
+	token := savePreview(t, db)
+
+	created := 0
+	create := func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+		created++
+		if len(p.Tracks) != 2 || p.Tracks[0].Uri != "track1" {
+			t.Errorf("Unexpected preview tracks: %v", p.Tracks)
+		}
+		return &model.CreatePlaylistResponse{PlaylistID: "playlist1", Seed: p.Seed}, nil
+	}
+
+	response, err := Commit(db, token, model.ServiceSpotify, "user1", create)
+	if err != nil {
+		t.Fatalf("first Commit() unexpected error: %v", err)
+	}
+	if response.Seed != 42 {
+		t.Errorf("Expected seed 42, got %d", response.Seed)
+	}
+
+	_, err = Commit(db, token, model.ServiceSpotify, "user1", create)
+	if !errors.Is(err, model.ErrPreviewNotFound) {
+		t.Errorf("Expected ErrPreviewNotFound on second commit, got %v", err)
+	}
+	if created != 1 {
+		t.Errorf("Expected playlist to be created once, got %d", created)
+	}
+}
+
+// TestCommit_Concurrent は、同じプレビューを同時に作成しても1回しか作成されないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 同じトークンで10個の Commit を同時に実行する
+//   - 期待結果: 成功は1つだけ、残りは ErrPreviewNotFound
+func TestCommit_Concurrent(t *testing.T) {
+	db := newTestDB(t)
+	token := savePreview(t, db)
+
+	var created, notFound atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Commit(db, token, model.ServiceSpotify, "user1", func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+				created.Add(1)
+				return &model.CreatePlaylistResponse{PlaylistID: "playlist1"}, nil
+			})
+			if errors.Is(err, model.ErrPreviewNotFound) {
+				notFound.Add(1)
+			} else if err != nil {
+				t.Errorf("Commit() unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created.Load() != 1 || notFound.Load() != 9 {
+		t.Errorf("Expected 1 created and 9 not found, got %d created and %d not found", created.Load(), notFound.Load())
+	}
+}
+
+// TestCommit_CreateFailed は、作成に失敗した場合にプレビューが戻ることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1回目は作成に失敗し、2回目は成功する
+//   - 期待結果: 1回目は作成のエラー、2回目は同じトークンで成功
+//   - 他のユーザーのトークンでは ErrPreviewNotFound
+func TestCommit_CreateFailed(t *testing.T) {
+	db := newTestDB(t)
+	token := savePreview(t, db)
+	errCreate := errors.New("spotify unavailable")
+
+	_, err := Commit(db, token, model.ServiceSpotify, "user1", func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+		return nil, errCreate
+	})
+	if !errors.Is(err, errCreate) {
+		t.Fatalf("Expected create error, got %v", err)
+	}
+
+	_, err = Commit(db, token, model.ServiceSpotify, "user2", func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+		t.Error("create must not be called for another user's preview")
+		return nil, nil
+	})
+	if !errors.Is(err, model.ErrPreviewNotFound) {
+		t.Errorf("Expected ErrPreviewNotFound for another user, got %v", err)
+	}
+
+	if _, err := Commit(db, token, model.ServiceSpotify, "user1", func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+		return &model.CreatePlaylistResponse{PlaylistID: "playlist1"}, nil
+	}); err != nil {
+		t.Errorf("Expected retry to succeed, got %v", err)
+	}
+}
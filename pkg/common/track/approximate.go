@@ -0,0 +1,89 @@
+package track
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// Approximation は許容範囲内の組み合わせが見つからず、
+// 指定時間に最も近い組み合わせで代用したことを表す
+type Approximation struct {
+	// Cause は近似しなければ返していたエラーコード
+	// （model.CodeTimeoutInsufficientTracks / model.CodeTimeoutNoMatch）
+	Cause       string
+	RequiredMs  int
+	TotalMs     int
+	ToleranceMs int
+}
+
+// DeltaMs は指定時間との差（正: 指定時間より長い、負: 短い）
+func (a *Approximation) DeltaMs() int {
+	return a.TotalMs - a.RequiredMs
+}
+
+// Details はレスポンスの details に出す説明を返す。近似でない場合は nil を返す。
+func (a *Approximation) Details() map[string]interface{} {
+	if a == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"reason":       "approximate",
+		"cause":        a.Cause,
+		"required_ms":  a.RequiredMs,
+		"total_ms":     a.TotalMs,
+		"delta_ms":     a.DeltaMs(),
+		"tolerance_ms": a.ToleranceMs,
+	}
+}
+
+// approximate は許容範囲内の組み合わせが見つからなかった場合に、範囲を広げて
+// 指定時間に最も近い組み合わせを探す。厳密な選曲とは別に制限時間を設ける。
+// 近似で代用できないエラーの場合や、1曲も選べない場合は元のエラーを返す。
+func (s *Selector) approximate(ctx context.Context, pool []model.Track, w window, cause error) ([]model.Track, int, int, error) {
+	if approximationCause(cause) == "" {
+		return nil, 0, 0, cause
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	tracks, attempts, recentExcluded, err := s.solveAvoidingRecent(ctx, pool, approximateWindow(w, s.opts.Mode), true)
+	if err != nil || len(tracks) == 0 {
+		return nil, attempts, 0, cause
+	}
+	return tracks, attempts, recentExcluded, nil
+}
+
+// approximateWindow は近似で探す範囲を返す。
+// 下限をなくし、上限を MaxApproximateOverMs まで広げる
+// （under-only では指定時間を超えず、over-only では指定時間より短くしない）。
+// 範囲内で指定時間に最も近い合計が選ばれる。
+func approximateWindow(w window, mode FitMode) window {
+	approx := window{
+		target: w.target,
+		lo:     0,
+		hi:     w.target + MaxApproximateOverMs,
+	}
+	switch mode {
+	case FitModeUnderOnly:
+		approx.hi = w.target
+	case FitModeOverOnly:
+		approx.lo = w.target
+	}
+	return approx
+}
+
+// approximationCause は選曲のエラーに対応するエラーコードを返す。
+// 近似で代用できないエラーの場合は空文字を返す。
+func approximationCause(err error) string {
+	switch {
+	case errors.Is(err, model.ErrNotEnoughTracks):
+		return model.CodeTimeoutInsufficientTracks
+	case errors.Is(err, model.ErrTimeoutCreatePlaylist):
+		return model.CodeTimeoutNoMatch
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,168 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 近似モード（allowApproximate）のテスト
+// =============================================================================
+// Options.AllowApproximate が指定された場合に、Selector が以下を満たすことをテストする:
+// 1. 再生時間が足りない場合はプール全体で最も近い組み合わせを返す
+// 2. 許容範囲内の組み合わせがない場合は指定時間に最も近い組み合わせを返す
+// 3. under-only では指定時間を超えない
+// 4. 指定がない場合はエラーの details に理由を付ける
+// =============================================================================
+
+// fourMinuteTracks は4分の曲を3曲返すテスト用ヘルパー（合計は8分か12分のどちらか）
+func fourMinuteTracks() []model.Track {
+	return []model.Track{
+		{Uri: "track1", DurationMs: 240000},
+		{Uri: "track2", DurationMs: 240000},
+		{Uri: "track3", DurationMs: 240000},
+	}
+}
+
+// TestSelector_Approximate_NotEnoughTracks は、再生時間が足りない場合に
+// すべての曲を使った近似結果を返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲（合計12分）
+//   - 要求: 20分、allowApproximate
+//   - 期待結果: 成功、3曲、delta_ms = -480000、cause = TIMEOUT_INSUFFICIENT_TRACKS
+func TestSelector_Approximate_NotEnoughTracks(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1, AllowApproximate: true}).Select(context.Background(), fourMinuteTracks(), 20*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if len(result.Tracks) != 3 {
+		t.Errorf("Expected 3 tracks, got %d", len(result.Tracks))
+	}
+	if result.Approximation == nil {
+		t.Fatal("Expected an approximation")
+	}
+	if result.Approximation.DeltaMs() != -480000 {
+		t.Errorf("Expected delta -480000, got %d", result.Approximation.DeltaMs())
+	}
+	if result.Approximation.Cause != model.CodeTimeoutInsufficientTracks {
+		t.Errorf("Expected cause %s, got %s", model.CodeTimeoutInsufficientTracks, result.Approximation.Cause)
+	}
+}
+
+// TestSelector_Approximate_NoMatch は、許容範囲内の組み合わせがない場合に
+// 指定時間に最も近い組み合わせを返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲
+//   - 要求: 10分30秒（±15秒）、allowApproximate
+//   - 期待結果: 成功、12分（8分より近い）、delta_ms = +90000、details.reason = "approximate"
+func TestSelector_Approximate_NoMatch(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1, AllowApproximate: true}).Select(context.Background(), fourMinuteTracks(), 630000)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.TotalMs != 720000 {
+		t.Errorf("Expected total 720000ms, got %d", result.TotalMs)
+	}
+	details := result.Approximation.Details()
+	if details["reason"] != "approximate" || details["delta_ms"] != 90000 || details["cause"] != model.CodeTimeoutNoMatch {
+		t.Errorf("Unexpected details: %v", details)
+	}
+}
+
+// TestSelector_Approximate_UnderOnly は、under-only の近似で指定時間を超えないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲
+//   - 要求: 10分30秒、under-only、allowApproximate
+//   - 期待結果: 成功、8分、delta_ms = -150000
+func TestSelector_Approximate_UnderOnly(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1, Mode: FitModeUnderOnly, AllowApproximate: true}).Select(context.Background(), fourMinuteTracks(), 630000)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.TotalMs != 480000 {
+		t.Errorf("Expected total 480000ms, got %d", result.TotalMs)
+	}
+	if result.Approximation.DeltaMs() != -150000 {
+		t.Errorf("Expected delta -150000, got %d", result.Approximation.DeltaMs())
+	}
+}
+
+// TestSelector_Approximate_OverOnly は、over-only の近似で指定時間より短くしないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲
+//   - 要求: 8分30秒、over-only、allowApproximate
+//   - 期待結果: 成功、12分（8分のほうが近いが指定時間より短い）、delta_ms = +210000
+//   - 要求: 13分、over-only、allowApproximate → 12分より長くできないので ErrNotEnoughTracks
+func TestSelector_Approximate_OverOnly(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1, Mode: FitModeOverOnly, AllowApproximate: true}).Select(context.Background(), fourMinuteTracks(), 510000)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.TotalMs != 720000 {
+		t.Errorf("Expected total 720000ms, got %d", result.TotalMs)
+	}
+	if result.Approximation.DeltaMs() != 210000 {
+		t.Errorf("Expected delta 210000, got %d", result.Approximation.DeltaMs())
+	}
+
+	_, err = NewSelector(Options{Seed: 1, Mode: FitModeOverOnly, AllowApproximate: true}).Select(context.Background(), fourMinuteTracks(), 13*MillisecondsPerMinute)
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Errorf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+}
+
+// TestSelector_Approximate_WithinTolerance は、許容範囲内で選べた場合は近似扱いにしないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲
+//   - 要求: 12分、allowApproximate
+//   - 期待結果: 成功、Approximation = nil、Details() = nil
+func TestSelector_Approximate_WithinTolerance(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1, AllowApproximate: true}).Select(context.Background(), fourMinuteTracks(), 12*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.Approximation != nil {
+		t.Errorf("Expected no approximation, got %+v", result.Approximation)
+	}
+	if result.Approximation.Details() != nil {
+		t.Errorf("Expected nil details")
+	}
+}
+
+// TestSelector_NoMatch_Details は、近似の指定がない場合にエラーの details に理由が付くことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲
+//   - 要求: 10分30秒 / 20分
+//   - 期待結果: ErrTimeoutCreatePlaylist（reason = "no_match"）/ ErrNotEnoughTracks（reason = "insufficient_duration"）
+func TestSelector_NoMatch_Details(t *testing.T) {
+	_, err := NewSelector(Options{Seed: 1}).Select(context.Background(), fourMinuteTracks(), 630000)
+	if !errors.Is(err, model.ErrTimeoutCreatePlaylist) {
+		t.Fatalf("Expected ErrTimeoutCreatePlaylist, got %v", err)
+	}
+	if details := model.ErrorDetails(err); details["reason"] != "no_match" {
+		t.Errorf("Unexpected error details: %v", details)
+	}
+
+	_, err = NewSelector(Options{Seed: 1}).Select(context.Background(), fourMinuteTracks(), 20*MillisecondsPerMinute)
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Fatalf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+	details := model.ErrorDetails(err)
+	if details["reason"] != "insufficient_duration" || details["available_ms"] != 720000 {
+		t.Errorf("Unexpected error details: %v", details)
+	}
+}
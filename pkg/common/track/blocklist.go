@@ -0,0 +1,60 @@
+package track
+
+import (
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// Blocklist はユーザーが選曲から除外した曲とアーティストの集合
+type Blocklist struct {
+	tracks  map[string]bool
+	artists map[string]bool
+}
+
+// NewBlocklist は除外リストの項目から Blocklist を生成する
+func NewBlocklist(items []model.BlocklistItem) *Blocklist {
+	b := &Blocklist{
+		tracks:  make(map[string]bool),
+		artists: make(map[string]bool),
+	}
+	for _, item := range items {
+		switch item.Type {
+		case model.BlocklistTypeTrack:
+			b.tracks[item.ID] = true
+		case model.BlocklistTypeArtist:
+			b.artists[item.ID] = true
+		}
+	}
+	return b
+}
+
+// Blocks は曲が除外対象かを返す。
+// 曲は URI（Spotify）または ID（SoundCloud）で、アーティストは参加アーティストのいずれかで判定する。
+func (b *Blocklist) Blocks(t model.Track) bool {
+	if b == nil {
+		return false
+	}
+	if b.tracks[t.Uri] || (t.ID != "" && b.tracks[t.ID]) {
+		return true
+	}
+	for _, id := range t.ArtistsId {
+		if b.artists[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeBlocked は候補プールから除外リストに含まれる曲を除き、除外した曲数とともに返す
+func excludeBlocked(pool []model.Track, b *Blocklist) ([]model.Track, int) {
+	if b == nil || (len(b.tracks) == 0 && len(b.artists) == 0) {
+		return pool, 0
+	}
+
+	filtered := make([]model.Track, 0, len(pool))
+	for _, t := range pool {
+		if !b.Blocks(t) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, len(pool) - len(filtered)
+}
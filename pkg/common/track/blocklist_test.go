@@ -0,0 +1,79 @@
+package track
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 除外リスト（Blocklist）のテスト
+// =============================================================================
+// Blocklist はユーザーが除外した曲とアーティストを候補プールから取り除く。
+// 以下のロジックをテストする:
+// 1. 曲は URI（Spotify）または ID（SoundCloud）で一致する
+// 2. アーティストは参加アーティストのいずれかが一致すれば除外される
+// 3. Selector は除外した曲を選ばない
+// =============================================================================
+
+// TestBlocklist_Blocks は、除外対象の判定をテストする。
+func TestBlocklist_Blocks(t *testing.T) {
+	b := NewBlocklist([]model.BlocklistItem{
+		{Type: model.BlocklistTypeTrack, ID: "spotify:track:disliked"},
+		{Type: model.BlocklistTypeTrack, ID: "12345"},
+		{Type: model.BlocklistTypeArtist, ID: "artistX"},
+	})
+
+	tests := []struct {
+		name     string
+		track    model.Track
+		expected bool
+	}{
+		{"Spotify URI", model.Track{Uri: "spotify:track:disliked"}, true},
+		{"SoundCloud ID", model.Track{Uri: "https://soundcloud.com/a/b", ID: "12345"}, true},
+		{"featured artist", model.Track{Uri: "spotify:track:feat", ArtistsId: []string{"artistA", "artistX"}}, true},
+		{"not blocked", model.Track{Uri: "spotify:track:ok", ArtistsId: []string{"artistA"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := b.Blocks(tt.track); result != tt.expected {
+				t.Errorf("Blocks() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSelector_Blocklist は、除外リストに含まれる曲が選ばれないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: artistX の曲5曲 + artistA の曲5曲（すべて2分）
+//   - 除外: artistX
+//   - 要求: 10分
+//   - 期待結果: 成功、artistA の曲のみ、diagnostics.blocked = 5
+func TestSelector_Blocklist(t *testing.T) {
+	var pool []model.Track
+	for i := 0; i < 5; i++ {
+		pool = append(pool,
+			model.Track{Uri: fmt.Sprintf("x%d", i), DurationMs: 120000, ArtistsId: []string{"artistX"}},
+			model.Track{Uri: fmt.Sprintf("a%d", i), DurationMs: 120000, ArtistsId: []string{"artistA"}},
+		)
+	}
+	b := NewBlocklist([]model.BlocklistItem{{Type: model.BlocklistTypeArtist, ID: "artistX"}})
+
+	result, err := NewSelector(Options{Seed: 1, Blocklist: b}).Select(context.Background(), pool, 10*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	for _, track := range result.Tracks {
+		if track.ArtistsId[0] == "artistX" {
+			t.Errorf("Blocked track %s was selected", track.Uri)
+		}
+	}
+	if result.Diagnostics.Blocked != 5 {
+		t.Errorf("Expected 5 blocked, got %d", result.Diagnostics.Blocked)
+	}
+}
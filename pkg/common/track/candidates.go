@@ -0,0 +1,154 @@
+package track
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// 一度に返せる候補数の上限
+const MaxCandidates = 10
+
+// 重複しない候補を集めるために、1候補あたり何回まで選曲を試すか
+const candidateAttemptsPerCandidate = 3
+
+// Candidate は SelectCandidates が返す選曲候補の1つ
+type Candidate struct {
+	Result
+	// Seed はこの候補の選曲に使ったシード
+	Seed int64
+	// GapMs は指定時間との差（TotalMs - targetMs）
+	GapMs int
+	// ArtistDiversity は曲数に対する異なるアーティスト数の割合（0〜1）
+	ArtistDiversity float64
+	// Freshness は最近のプレイリストで使っていない曲の割合（0〜1）
+	Freshness float64
+}
+
+// SelectCandidates は同じ候補プールから曲の組み合わせが異なる候補を最大 k 件選び、
+// 指定時間との差が小さい順、アーティストの多様性が高い順、最近使った曲が少ない順に並べて返す。
+// 候補プールの取得は呼び出し側で1回だけ行えばよい。
+// 各候補は opts.Seed から導いたシードで選曲するため、同じシードなら同じ結果になる。
+// 全候補の選曲を合わせて opts.Timeout（0の場合は DefaultTimeoutSeconds）以内に収めるため、
+// 各候補の選曲には残り時間をまだ必要な候補数で分けた時間を割り当てる。
+// 1件も選べなかった場合は最初の選曲エラーを返す。
+func SelectCandidates(ctx context.Context, pool []model.Track, targetMs int, k int, opts Options) ([]Candidate, error) {
+	if k <= 0 {
+		k = 1
+	}
+	if k > MaxCandidates {
+		k = MaxCandidates
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = time.Duration(DefaultTimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	seeds := rand.New(rand.NewSource(opts.Seed))
+	recent := make(map[string]bool)
+	for _, tracks := range opts.Recent {
+		for _, t := range tracks {
+			recent[trackKey(t)] = true
+		}
+	}
+
+	var candidates []Candidate
+	var firstErr error
+	seen := make(map[string]bool)
+	for i := 0; i < k*candidateAttemptsPerCandidate && len(candidates) < k; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// 1件目はリクエストのシードをそのまま使い、作成APIと同じ結果にする
+		candOpts := opts
+		if i > 0 {
+			candOpts.Seed = seeds.Int63n(MaxSeed)
+		}
+		candOpts.Timeout = time.Until(deadline) / time.Duration(k-len(candidates))
+		if candOpts.Timeout <= 0 {
+			break
+		}
+		result, err := NewSelector(candOpts).Select(ctx, pool, targetMs)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		key := trackSetKey(result.Tracks)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, newCandidate(*result, candOpts.Seed, targetMs, recent))
+	}
+
+	if len(candidates) == 0 {
+		if firstErr == nil {
+			firstErr = ctx.Err()
+		}
+		return nil, firstErr
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if abs(a.GapMs) != abs(b.GapMs) {
+			return abs(a.GapMs) < abs(b.GapMs)
+		}
+		if a.ArtistDiversity != b.ArtistDiversity {
+			return a.ArtistDiversity > b.ArtistDiversity
+		}
+		return a.Freshness > b.Freshness
+	})
+	return candidates, nil
+}
+
+// newCandidate は選曲結果から順位付けに使う指標を計算する
+func newCandidate(result Result, seed int64, targetMs int, recent map[string]bool) Candidate {
+	c := Candidate{
+		Result:          result,
+		Seed:            seed,
+		GapMs:           result.TotalMs - targetMs,
+		ArtistDiversity: 1,
+		Freshness:       1,
+	}
+	if len(result.Tracks) == 0 {
+		return c
+	}
+
+	artists := make(map[string]bool)
+	fresh := 0
+	for _, t := range result.Tracks {
+		for _, id := range t.ArtistsId {
+			artists[id] = true
+		}
+		if !recent[trackKey(t)] {
+			fresh++
+		}
+	}
+	if len(artists) < len(result.Tracks) {
+		c.ArtistDiversity = float64(len(artists)) / float64(len(result.Tracks))
+	}
+	c.Freshness = float64(fresh) / float64(len(result.Tracks))
+	return c
+}
+
+// trackSetKey は曲の並び順によらない組み合わせのキーを返す
+func trackSetKey(tracks []model.Track) string {
+	keys := make([]string, len(tracks))
+	for i, t := range tracks {
+		keys[i] = trackKey(t)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\n")
+}
@@ -0,0 +1,167 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 複数候補の選曲のテスト
+// =============================================================================
+// SelectCandidates が以下を満たすことをテストする:
+// 1. 曲の組み合わせが重複しない候補を返す
+// 2. 指定時間との差、アーティストの多様性、新しさの順に並べる
+// 3. 1件も選べない場合は選曲エラーを返す
+// 4. 全候補の選曲を合わせて1回分の制限時間に収める
+// =============================================================================
+
+// TestSelectCandidates_Distinct は、候補の曲の組み合わせが重複しないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲20曲
+//   - 要求: 5分、3候補
+//   - 期待結果: 3候補、すべて5分、組み合わせが互いに異なる
+func TestSelectCandidates_Distinct(t *testing.T) {
+	pool := minuteTracks("track", 20)
+
+	candidates, err := SelectCandidates(context.Background(), pool, 5*MillisecondsPerMinute, 3, Options{Seed: 1})
+
+	if err != nil {
+		t.Fatalf("SelectCandidates() unexpected error: %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d", len(candidates))
+	}
+	seen := make(map[string]bool)
+	for i, c := range candidates {
+		if c.TotalMs != 5*MillisecondsPerMinute {
+			t.Errorf("Candidate %d: expected total 300000ms, got %d", i, c.TotalMs)
+		}
+		key := trackSetKey(c.Tracks)
+		if seen[key] {
+			t.Errorf("Candidate %d duplicates an earlier candidate", i)
+		}
+		seen[key] = true
+	}
+}
+
+// TestSelectCandidates_FirstMatchesSelect は、最初の選曲がリクエストのシードで
+// Select した結果と同じになることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲20曲、シード42
+//   - 要求: 5分、1候補
+//   - 期待結果: Select(Seed=42) と同じ曲、候補のシードは42
+func TestSelectCandidates_FirstMatchesSelect(t *testing.T) {
+	pool := minuteTracks("track", 20)
+	opts := Options{Seed: 42}
+
+	want, err := NewSelector(opts).Select(context.Background(), pool, 5*MillisecondsPerMinute)
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	candidates, err := SelectCandidates(context.Background(), pool, 5*MillisecondsPerMinute, 1, opts)
+	if err != nil {
+		t.Fatalf("SelectCandidates() unexpected error: %v", err)
+	}
+
+	if candidates[0].Seed != 42 {
+		t.Errorf("Expected seed 42, got %d", candidates[0].Seed)
+	}
+	if trackSetKey(candidates[0].Tracks) != trackSetKey(want.Tracks) {
+		t.Errorf("Expected the same tracks as Select()")
+	}
+}
+
+// TestSelectCandidates_Scores は、順位付けに使う多様性と新しさの指標をテストする。
+//
+// テストシナリオ:
+//   - 入力: 同じアーティストの2曲、最近使った曲を含む2曲、どちらでもない2曲
+//   - 期待結果: 多様性 0.5 / 新しさ 0.5 / どちらも 1
+func TestSelectCandidates_Scores(t *testing.T) {
+	recent := map[string]bool{"old": true}
+	track := func(uri, artist string) model.Track {
+		return model.Track{Uri: uri, DurationMs: 60000, ArtistsId: []string{artist}}
+	}
+
+	sameArtist := newCandidate(Result{Tracks: []model.Track{track("a", "x"), track("b", "x")}, TotalMs: 120000}, 1, 120000, recent)
+	withRecent := newCandidate(Result{Tracks: []model.Track{track("old", "x"), track("c", "y")}, TotalMs: 120000}, 2, 120000, recent)
+	best := newCandidate(Result{Tracks: []model.Track{track("d", "x"), track("e", "y")}, TotalMs: 120000}, 3, 120000, recent)
+
+	if sameArtist.ArtistDiversity != 0.5 {
+		t.Errorf("Expected diversity 0.5, got %v", sameArtist.ArtistDiversity)
+	}
+	if withRecent.Freshness != 0.5 {
+		t.Errorf("Expected freshness 0.5, got %v", withRecent.Freshness)
+	}
+	if best.ArtistDiversity != 1 || best.Freshness != 1 {
+		t.Errorf("Expected diversity and freshness 1, got %v, %v", best.ArtistDiversity, best.Freshness)
+	}
+}
+
+// TestSelectCandidates_NotEnoughTracks は、1件も選べない場合にエラーを返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲3曲
+//   - 要求: 10分、3候補
+//   - 期待結果: ErrNotEnoughTracks
+func TestSelectCandidates_NotEnoughTracks(t *testing.T) {
+	pool := minuteTracks("track", 3)
+
+	_, err := SelectCandidates(context.Background(), pool, 10*MillisecondsPerMinute, 3, Options{Seed: 1})
+
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Errorf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+}
+
+// TestSelectCandidates_FewerThanRequested は、組み合わせが k 通りない場合に
+// 見つかった分だけ返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲5曲
+//   - 要求: 5分、5候補
+//   - 期待結果: 1候補（組み合わせは1通りしかない）
+func TestSelectCandidates_FewerThanRequested(t *testing.T) {
+	pool := minuteTracks("track", 5)
+
+	candidates, err := SelectCandidates(context.Background(), pool, 5*MillisecondsPerMinute, 5, Options{Seed: 1})
+
+	if err != nil {
+		t.Fatalf("SelectCandidates() unexpected error: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Errorf("Expected 1 candidate, got %d", len(candidates))
+	}
+}
+
+// TestSelectCandidates_SharedTimeout は、組み合わせが見つからず各選曲が制限時間まで探索する場合でも、
+// 全候補の選曲が opts.Timeout 程度で終わることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3000曲（厳密探索できない規模）
+//   - 要求: 61分30秒（許容誤差15秒では4分の倍数に合わない）、10候補、制限時間200ms
+//   - 期待結果: ErrTimeoutCreatePlaylist、候補ごとに200ms（最大30回で6秒）ではなく1秒未満で終わる
+func TestSelectCandidates_SharedTimeout(t *testing.T) {
+	pool := make([]model.Track, 3000)
+	for i := range pool {
+		pool[i] = model.Track{Uri: fmt.Sprintf("track%d", i), DurationMs: 4 * MillisecondsPerMinute}
+	}
+	targetMs := 61*MillisecondsPerMinute + 30000
+
+	start := time.Now()
+	_, err := SelectCandidates(context.Background(), pool, targetMs, 10, Options{Seed: 1, Timeout: 200 * time.Millisecond})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, model.ErrTimeoutCreatePlaylist) {
+		t.Errorf("Expected ErrTimeoutCreatePlaylist, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Expected candidates to share one timeout, took %s", elapsed)
+	}
+}
@@ -14,7 +14,16 @@ const (
 	// 15秒 = 15000ms
 	AllowanceMs = 15 * MillisecondsPerSecond
 
+	// 指定できる再生時間の範囲
+	// 最小30秒、最大24時間
+	MinPlaylistDurationMs = 30 * MillisecondsPerSecond
+	MaxPlaylistDurationMs = 24 * 60 * MillisecondsPerMinute
+
 	// 許容誤差を適用する最小再生時間
 	// 10分 = 600000ms
 	MinPlaylistDurationForAllowanceMs = 10 * MillisecondsPerMinute
+
+	// 近似モードで指定時間を超えてよい上限
+	// 10分 = 600000ms
+	MaxApproximateOverMs = 10 * MillisecondsPerMinute
 )
@@ -0,0 +1,177 @@
+package track
+
+import (
+	"context"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// クロスフェードで補正するエラーの details の項目（いずれも実際の再生時間に直す）
+var crossfadeDetailKeys = []string{"required_ms", "available_ms", "pinned_ms", "total_ms"}
+
+// crossfade はクロスフェードによる曲の重なりを考慮して再生時間を合わせるための変換。
+//
+// n 曲を crossfadeMs ずつ重ねて再生すると、実際の再生時間は
+// 「曲の長さの合計 - (n-1)×crossfadeMs」=「(各曲の長さ - crossfadeMs) の合計 + crossfadeMs」になる。
+// そこで各曲の長さから crossfadeMs を引いた候補プールで「指定時間 - crossfadeMs」に合わせれば、
+// 曲数によらず実際の再生時間が指定時間に合う。選んだ曲は restore で元の長さに戻す。
+type crossfade struct {
+	ms        int
+	originals map[string]int
+}
+
+// newCrossfade は crossfadeMs の変換を生成する
+func newCrossfade(crossfadeMs int) *crossfade {
+	return &crossfade{ms: crossfadeMs, originals: make(map[string]int)}
+}
+
+// shorten は各曲の長さから重なりの分を引いた候補プールを返す。
+// 重なりより短い曲はクロスフェードで再生できないため候補から外す。
+func (c *crossfade) shorten(tracks []model.Track) []model.Track {
+	shortened := make([]model.Track, 0, len(tracks))
+	for _, t := range tracks {
+		if t.DurationMs <= c.ms {
+			continue
+		}
+		c.originals[trackKey(t)] = t.DurationMs
+		t.DurationMs -= c.ms
+		shortened = append(shortened, t)
+	}
+	return shortened
+}
+
+// shortenPins は固定曲の長さから重なりの分を引く
+func (c *crossfade) shortenPins(pins []Pin) []Pin {
+	shortened := make([]Pin, len(pins))
+	for i, p := range pins {
+		c.originals[trackKey(p.Track)] = p.Track.DurationMs
+		p.Track.DurationMs -= c.ms
+		shortened[i] = p
+	}
+	return shortened
+}
+
+// shortenGapFill は GapFill の候補プールの曲の長さから重なりの分を引く
+func (c *crossfade) shortenGapFill(gapFill GapFillFunc) GapFillFunc {
+	if gapFill == nil {
+		return nil
+	}
+	return func() ([][]model.Track, error) {
+		pools, err := gapFill()
+		if err != nil {
+			return nil, err
+		}
+		shortened := make([][]model.Track, len(pools))
+		for i, pool := range pools {
+			shortened[i] = c.shorten(pool)
+		}
+		return shortened, nil
+	}
+}
+
+// shortenPoolFunc は PoolFunc が返す候補プールの曲の長さから重なりの分を引く
+func (c *crossfade) shortenPoolFunc(poolFunc PoolFunc) PoolFunc {
+	return func(seg Segment) ([]model.Track, error) {
+		pool, err := poolFunc(seg)
+		if err != nil {
+			return nil, err
+		}
+		return c.shorten(pool), nil
+	}
+}
+
+// options は変換後の候補プールに合わせて曲の長さの範囲と固定曲を補正した Options を返す。
+// 許容誤差は補正前の指定時間 targetMs で決める。
+func (c *crossfade) options(opts Options, targetMs int) Options {
+	tolerance := opts.Policy(targetMs).ToleranceMs
+	opts.ToleranceMs = &tolerance
+	opts.CrossfadeMs = 0
+	if opts.MinTrackMs > 0 {
+		opts.MinTrackMs = max(opts.MinTrackMs-c.ms, 1)
+	}
+	if opts.MaxTrackMs > 0 {
+		opts.MaxTrackMs = max(opts.MaxTrackMs-c.ms, 1)
+	}
+	opts.Pins = c.shortenPins(opts.Pins)
+	opts.GapFill = c.shortenGapFill(opts.GapFill)
+	return opts
+}
+
+// restore は選んだ曲を元の長さに戻し、結果の再生時間を実際の再生時間に直す
+func (c *crossfade) restore(result *Result) {
+	for i := range result.Tracks {
+		if ms, ok := c.originals[trackKey(result.Tracks[i])]; ok {
+			result.Tracks[i].DurationMs = ms
+		}
+	}
+	if len(result.Tracks) > 0 {
+		result.TotalMs += c.ms
+	}
+	result.Diagnostics.PoolDurationMs += c.ms
+	if a := result.Approximation; a != nil {
+		a.RequiredMs += c.ms
+		a.TotalMs = result.TotalMs
+	}
+}
+
+// restoreError はエラーの details の再生時間を実際の再生時間に直し、crossfade_ms を加える
+func (c *crossfade) restoreError(err error) error {
+	src := model.ErrorDetails(err)
+	if src == nil {
+		return err
+	}
+	details := make(map[string]interface{}, len(src)+1)
+	for k, v := range src {
+		details[k] = v
+	}
+	for _, key := range crossfadeDetailKeys {
+		if ms, ok := details[key].(int); ok {
+			details[key] = ms + c.ms
+		}
+	}
+	if diag, ok := details["diagnostics"].(Diagnostics); ok {
+		diag.PoolDurationMs += c.ms
+		details["diagnostics"] = diag
+	}
+	details["crossfade_ms"] = c.ms
+	return model.WithDetails(err, details)
+}
+
+// selectCrossfaded はクロスフェードの重なりを考慮して、実際の再生時間が targetMs に合う曲を選ぶ
+func (s *Selector) selectCrossfaded(ctx context.Context, pool []model.Track, targetMs int) (*Result, error) {
+	c := newCrossfade(s.opts.CrossfadeMs)
+	inner := &Selector{opts: c.options(s.opts, targetMs), rng: s.rng}
+
+	result, err := inner.Select(ctx, c.shorten(pool), targetMs-c.ms)
+	if err != nil {
+		return nil, c.restoreError(err)
+	}
+	c.restore(result)
+	return result, nil
+}
+
+// fillMixCrossfaded はクロスフェードの重なりを考慮して FillMix を行う。
+// 変換後の曲の長さは選曲元をまたいで足し合わせられるため、全体を1回だけ補正すればよい。
+// 選曲元ごとの内訳は重なりを引いた再生時間で表す。
+func fillMixCrossfaded(ctx context.Context, parts []MixPart, targetMs int, opts Options, poolFunc PoolFunc) (*Result, []model.MixShare, error) {
+	c := newCrossfade(opts.CrossfadeMs)
+
+	result, shares, err := FillMix(ctx, parts, targetMs-c.ms, c.options(opts, targetMs), c.shortenPoolFunc(poolFunc))
+	if err != nil {
+		return nil, nil, c.restoreError(err)
+	}
+	c.restore(result)
+	return result, shares, nil
+}
+
+// EffectiveDurationMs は曲を crossfadeMs ずつ重ねて再生した場合の実際の再生時間を返す
+func EffectiveDurationMs(tracks []model.Track, crossfadeMs int) int {
+	total := 0
+	for _, t := range tracks {
+		total += t.DurationMs
+	}
+	if len(tracks) > 1 {
+		total -= (len(tracks) - 1) * crossfadeMs
+	}
+	return total
+}
@@ -0,0 +1,164 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// クロスフェード（crossfadeSeconds）のテスト
+// =============================================================================
+// CrossfadeMs が指定された場合に、以下を満たすことをテストする:
+// 1. 曲の重なりを引いた実際の再生時間を指定時間に合わせる
+// 2. 選んだ曲の長さは元の長さのまま返す
+// 3. 区間・配分による選曲でも全体の実際の再生時間が合う
+// 4. エラーの details は実際の再生時間で表す
+// =============================================================================
+
+// TestEffectiveDurationMs は重なりを引いた再生時間の計算をテストする。
+//
+// テストシナリオ:
+//   - 1分の曲5曲、重なり12秒 → 5分 - 4×12秒 = 252秒
+//   - 1曲のみ → 重ならない
+func TestEffectiveDurationMs(t *testing.T) {
+	if got := EffectiveDurationMs(minuteTracks("track", 5), 12000); got != 252000 {
+		t.Errorf("Expected 252000ms, got %d", got)
+	}
+	if got := EffectiveDurationMs(minuteTracks("track", 1), 12000); got != 60000 {
+		t.Errorf("Expected 60000ms for a single track, got %d", got)
+	}
+}
+
+// TestSelector_Crossfade は、重なりを引いた再生時間で合わせることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲20曲
+//   - 要求: 5分、クロスフェード12秒
+//   - 期待結果: 6曲（6分 - 5×12秒 = 5分）、曲の長さは1分のまま、TotalMs = 5分
+func TestSelector_Crossfade(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1, CrossfadeMs: 12000}).Select(context.Background(), minuteTracks("track", 20), 5*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if len(result.Tracks) != 6 {
+		t.Errorf("Expected 6 tracks, got %d", len(result.Tracks))
+	}
+	for _, track := range result.Tracks {
+		if track.DurationMs != 60000 {
+			t.Errorf("Track %s: expected original duration 60000ms, got %d", track.Uri, track.DurationMs)
+		}
+	}
+	if result.TotalMs != 5*MillisecondsPerMinute || EffectiveDurationMs(result.Tracks, 12000) != result.TotalMs {
+		t.Errorf("Expected effective total 300000ms, got %d", result.TotalMs)
+	}
+}
+
+// TestMakeTracksWithPolicy_Crossfade は、MakeTracks でも重なりを考慮することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲10曲
+//   - 要求: 5分、クロスフェード12秒
+//   - 期待結果: 成功、6曲、曲の長さは1分のまま
+func TestMakeTracksWithPolicy_Crossfade(t *testing.T) {
+	ok, tracks := MakeTracksWithPolicy(minuteTracks("track", 10), 5*MillisecondsPerMinute, FitPolicy{CrossfadeMs: 12000})
+
+	if !ok {
+		t.Fatal("Expected success")
+	}
+	if len(tracks) != 6 || tracks[0].DurationMs != 60000 {
+		t.Errorf("Unexpected tracks: %+v", tracks)
+	}
+}
+
+// TestGetTrackByDurationWithPolicy_Crossfade は、追加する曲が前の曲と重なる分を考慮することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 50秒と62秒の曲
+//   - 要求: 残り50秒、クロスフェード12秒
+//   - 期待結果: 62秒の曲（重なりを引くと50秒）
+func TestGetTrackByDurationWithPolicy_Crossfade(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "short", DurationMs: 50000},
+		{Uri: "long", DurationMs: 62000},
+	}
+
+	got := GetTrackByDurationWithPolicy(tracks, 50000, 5*MillisecondsPerMinute, FitPolicy{CrossfadeMs: 12000})
+
+	if len(got) != 1 || got[0].Uri != "long" {
+		t.Errorf("Expected the 62s track, got %+v", got)
+	}
+}
+
+// TestFillSegments_Crossfade は、区間の境界でも曲が重なることを考慮することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲20曲、区間は5分と4分48秒
+//   - クロスフェード12秒
+//   - 期待結果: 全体の実際の再生時間が9分48秒、区間の境界は5分、各区間の差は0
+func TestFillSegments_Crossfade(t *testing.T) {
+	segments := []Segment{{Source: SegmentSourceFavorites}, {Source: SegmentSourceFavorites}}
+	pools := map[string][]model.Track{SegmentSourceFavorites: minuteTracks("track", 20)}
+
+	tracks, boundaries, err := FillSegments(context.Background(), segments, []int{300000, 288000}, Options{Seed: 1, CrossfadeMs: 12000}, mixPoolFunc(pools))
+
+	if err != nil {
+		t.Fatalf("FillSegments() unexpected error: %v", err)
+	}
+	if got := EffectiveDurationMs(tracks, 12000); got != 588000 {
+		t.Errorf("Expected effective total 588000ms, got %d", got)
+	}
+	if boundaries[0].EndMs != 300000 || boundaries[1].EndMs != 588000 {
+		t.Errorf("Unexpected boundaries: %+v", boundaries)
+	}
+	if SegmentsDeltaMs(boundaries) != 0 {
+		t.Errorf("Expected no delta, got %d", SegmentsDeltaMs(boundaries))
+	}
+}
+
+// TestFillMix_Crossfade は、配分による選曲でも全体の実際の再生時間が合うことをテストする。
+//
+// テストシナリオ:
+//   - 入力: お気に入り1分の曲20曲、カタログ1分の曲20曲
+//   - 要求: 10分（±15秒）、favorites 50 / catalog 50、クロスフェード12秒
+//   - 期待結果: TotalMs が重なりを引いた再生時間と一致し、許容範囲内
+func TestFillMix_Crossfade(t *testing.T) {
+	pools := map[string][]model.Track{
+		SegmentSourceFavorites: minuteTracks("fav", 20),
+		SegmentSourceCatalog:   minuteTracks("cat", 20),
+	}
+	parts, _ := MixParams{Weights: MixWeights{Favorites: 50, Catalog: 50}}.Parts()
+
+	result, _, err := FillMix(context.Background(), parts, 10*MillisecondsPerMinute, Options{Seed: 1, CrossfadeMs: 12000}, mixPoolFunc(pools))
+
+	if err != nil {
+		t.Fatalf("FillMix() unexpected error: %v", err)
+	}
+	if EffectiveDurationMs(result.Tracks, 12000) != result.TotalMs {
+		t.Errorf("Expected TotalMs %d to be the effective duration %d", result.TotalMs, EffectiveDurationMs(result.Tracks, 12000))
+	}
+	if abs(result.TotalMs-10*MillisecondsPerMinute) > AllowanceMs {
+		t.Errorf("Expected effective total within 15s of 600000ms, got %d", result.TotalMs)
+	}
+}
+
+// TestSelector_Crossfade_Details は、エラーの details を実際の再生時間で表すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲（重ねると12分 - 2×12秒 = 696秒）
+//   - 要求: 20分、クロスフェード12秒
+//   - 期待結果: ErrNotEnoughTracks、required_ms = 1200000、available_ms = 696000、crossfade_ms = 12000
+func TestSelector_Crossfade_Details(t *testing.T) {
+	_, err := NewSelector(Options{Seed: 1, CrossfadeMs: 12000}).Select(context.Background(), fourMinuteTracks(), 20*MillisecondsPerMinute)
+
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Fatalf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+	details := model.ErrorDetails(err)
+	if details["required_ms"] != 1200000 || details["available_ms"] != 696000 || details["crossfade_ms"] != 12000 {
+		t.Errorf("Unexpected error details: %v", details)
+	}
+}
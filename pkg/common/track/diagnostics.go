@@ -0,0 +1,84 @@
+package track
+
+import (
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// 曲の長さの分布の区間幅（1分）と、上限なしの最後の区間の開始位置（10分）
+const (
+	histogramBucketMs = MillisecondsPerMinute
+	histogramMaxMs    = 10 * MillisecondsPerMinute
+)
+
+// durationHistogram は候補プールの曲の長さを1分ごとに数える（10分以上はまとめる）
+func durationHistogram(pool []model.Track) []model.DurationBucket {
+	buckets := make([]model.DurationBucket, histogramMaxMs/histogramBucketMs+1)
+	for i := range buckets {
+		buckets[i].FromMs = i * histogramBucketMs
+		if buckets[i].FromMs < histogramMaxMs {
+			buckets[i].ToMs = buckets[i].FromMs + histogramBucketMs
+		}
+	}
+	for _, t := range pool {
+		i := t.DurationMs / histogramBucketMs
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(buckets) {
+			i = len(buckets) - 1
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// topFilter は最も多くの候補を除外した条件を返す（どの条件でも除外していなければ空文字）
+func topFilter(diag Diagnostics) string {
+	top, most := "", 0
+	for _, f := range []struct {
+		name    string
+		removed int
+	}{
+		{"track_length", diag.FilteredOut},
+		{"blocklist", diag.Blocked},
+		{"recent", diag.RecentExcluded},
+	} {
+		if f.removed > most {
+			top, most = f.name, f.removed
+		}
+	}
+	return top
+}
+
+// withDiagnostics はエラーの details に診断情報を加える
+func withDiagnostics(err error, diag Diagnostics) error {
+	details := map[string]interface{}{}
+	for k, v := range model.ErrorDetails(err) {
+		details[k] = v
+	}
+	details["diagnostics"] = diag
+	return model.WithDetails(err, details)
+}
+
+// addDiagnostics は複数の選曲（選曲元ごと、区間ごと）の診断情報を1つにまとめるため、src を dst に足し合わせる。
+// RemainderMs は全体の指定時間から求めるため足さない。
+func addDiagnostics(dst *Diagnostics, src Diagnostics) {
+	dst.PoolSize += src.PoolSize
+	dst.FilteredOut += src.FilteredOut
+	dst.Blocked += src.Blocked
+	dst.RecentExcluded += src.RecentExcluded
+	dst.GapFilled += src.GapFilled
+	dst.PoolDurationMs += src.PoolDurationMs
+	dst.Attempts += src.Attempts
+	dst.ElapsedMs += src.ElapsedMs
+	if dst.DurationHistogram == nil {
+		dst.DurationHistogram = append([]model.DurationBucket(nil), src.DurationHistogram...)
+	} else {
+		for i := range src.DurationHistogram {
+			if i < len(dst.DurationHistogram) {
+				dst.DurationHistogram[i].Count += src.DurationHistogram[i].Count
+			}
+		}
+	}
+	dst.TopFilter = topFilter(*dst)
+}
@@ -0,0 +1,135 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 選曲の診断情報のテスト
+// =============================================================================
+// Selector が返す診断情報が以下を満たすことをテストする:
+// 1. 候補プールの曲の長さを1分ごとに数える（10分以上はまとめる）
+// 2. 最も多くの候補を除外した条件を記録する
+// 3. 指定時間との残りを記録する
+// 4. Debug 指定時は選曲エラーの details に診断情報を含める
+// =============================================================================
+
+// TestDurationHistogram は曲の長さの分布をテストする。
+//
+// テストシナリオ:
+//   - 入力: 30秒、1分30秒、1分59秒、12分の曲
+//   - 期待結果: [0,1分) = 1、[1分,2分) = 2、10分以上 = 1、最後の区間は上限なし
+func TestDurationHistogram(t *testing.T) {
+	pool := []model.Track{
+		{Uri: "a", DurationMs: 30000},
+		{Uri: "b", DurationMs: 90000},
+		{Uri: "c", DurationMs: 119000},
+		{Uri: "d", DurationMs: 720000},
+	}
+
+	buckets := durationHistogram(pool)
+
+	if len(buckets) != 11 {
+		t.Fatalf("Expected 11 buckets, got %d", len(buckets))
+	}
+	if buckets[0].Count != 1 || buckets[1].Count != 2 || buckets[10].Count != 1 {
+		t.Errorf("Unexpected buckets: %+v", buckets)
+	}
+	if buckets[1].FromMs != 60000 || buckets[1].ToMs != 120000 {
+		t.Errorf("Unexpected bucket range: %+v", buckets[1])
+	}
+	if buckets[10].FromMs != 600000 || buckets[10].ToMs != 0 {
+		t.Errorf("Expected open-ended last bucket, got %+v", buckets[10])
+	}
+}
+
+// TestSelector_Diagnostics_TopFilter は、最も多くの候補を除外した条件を記録することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲10曲と30秒の曲5曲、1分の曲のうち3曲を除外リストに指定
+//   - 最短曲の指定なし → top_filter = "blocklist"
+//   - 最短曲を45秒に指定（30秒の曲5曲が範囲外） → top_filter = "track_length"
+func TestSelector_Diagnostics_TopFilter(t *testing.T) {
+	pool := minuteTracks("track", 10)
+	for i := 0; i < 5; i++ {
+		pool = append(pool, model.Track{Uri: fmt.Sprintf("short%d", i), DurationMs: 30000})
+	}
+	blocklist := NewBlocklist([]model.BlocklistItem{
+		{Type: model.BlocklistTypeTrack, ID: "track0"},
+		{Type: model.BlocklistTypeTrack, ID: "track1"},
+		{Type: model.BlocklistTypeTrack, ID: "track2"},
+	})
+
+	result, err := NewSelector(Options{Seed: 1, Blocklist: blocklist}).Select(context.Background(), pool, 5*MillisecondsPerMinute)
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.Diagnostics.TopFilter != "blocklist" {
+		t.Errorf("Expected top filter blocklist, got %q", result.Diagnostics.TopFilter)
+	}
+
+	result, err = NewSelector(Options{Seed: 1, Blocklist: blocklist, MinTrackMs: 45000}).Select(context.Background(), pool, 5*MillisecondsPerMinute)
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.Diagnostics.TopFilter != "track_length" {
+		t.Errorf("Expected top filter track_length, got %q", result.Diagnostics.TopFilter)
+	}
+}
+
+// TestSelector_Diagnostics_Remainder は、指定時間との残りを記録することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲
+//   - 要求: 12分
+//   - 期待結果: remainder_ms = 0、分布の [4分,5分) = 3
+func TestSelector_Diagnostics_Remainder(t *testing.T) {
+	result, err := NewSelector(Options{Seed: 1}).Select(context.Background(), fourMinuteTracks(), 12*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.Diagnostics.RemainderMs != 0 {
+		t.Errorf("Expected remainder 0, got %d", result.Diagnostics.RemainderMs)
+	}
+	if result.Diagnostics.DurationHistogram[4].Count != 3 {
+		t.Errorf("Unexpected histogram: %+v", result.Diagnostics.DurationHistogram)
+	}
+}
+
+// TestSelector_Diagnostics_Debug は、Debug 指定時に選曲エラーの details に診断情報が入ることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲（合計12分）
+//   - 要求: 20分、debug
+//   - 期待結果: ErrNotEnoughTracks、details.reason = "insufficient_duration"、
+//     details.diagnostics.remainder_ms = 480000
+//   - Debug なしの場合は details に diagnostics を含めない
+func TestSelector_Diagnostics_Debug(t *testing.T) {
+	_, err := NewSelector(Options{Seed: 1, Debug: true}).Select(context.Background(), fourMinuteTracks(), 20*MillisecondsPerMinute)
+
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Fatalf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+	details := model.ErrorDetails(err)
+	if details["reason"] != "insufficient_duration" {
+		t.Errorf("Expected reason to be kept, got %v", details)
+	}
+	diag, ok := details["diagnostics"].(Diagnostics)
+	if !ok {
+		t.Fatalf("Expected diagnostics in details, got %v", details)
+	}
+	if diag.RemainderMs != 480000 || diag.PoolSize != 3 {
+		t.Errorf("Unexpected diagnostics: %+v", diag)
+	}
+
+	_, err = NewSelector(Options{Seed: 1}).Select(context.Background(), fourMinuteTracks(), 20*MillisecondsPerMinute)
+	if _, ok := model.ErrorDetails(err)["diagnostics"]; ok {
+		t.Error("Diagnostics should not be included without debug")
+	}
+}
@@ -0,0 +1,148 @@
+package track
+
+import (
+	"math/rand"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// アーティストの多様性の制約を満たすまでの試行回数の上限
+const maxDiversityAttempts = 8
+
+// capPerArtist は1アーティストあたり max 曲までになるように候補プールを間引く。
+// どの曲を残すかは rng でランダムに決める。複数のアーティストが参加している曲は
+// 参加している全アーティストの曲数に数える。アーティスト情報のない曲は制限しない。
+// reserved（固定曲など、候補プールとは別に必ず含める曲）も曲数に数える。
+func capPerArtist(pool []model.Track, max int, reserved []model.Track, rng *rand.Rand) []model.Track {
+	order := rng.Perm(len(pool))
+	counts := make(map[string]int)
+	for _, t := range reserved {
+		for _, id := range t.ArtistsId {
+			counts[id]++
+		}
+	}
+	capped := make([]model.Track, 0, len(pool))
+
+	for _, i := range order {
+		t := pool[i]
+		ok := true
+		for _, id := range t.ArtistsId {
+			if counts[id] >= max {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		for _, id := range t.ArtistsId {
+			counts[id]++
+		}
+		capped = append(capped, t)
+	}
+	return capped
+}
+
+// orderNoConsecutiveArtist は同じアーティストの曲が隣り合わないように曲を並べ替える。
+// 残り曲数の多いアーティストから優先して置いていき、同数の場合は rng で選ぶ。
+// prev / next が指定された場合は、並べた曲の前後に置かれる曲（先頭・末尾の固定曲）とも隣り合わないようにする。
+// どう並べても隣り合ってしまう場合は false を返す。
+func orderNoConsecutiveArtist(tracks []model.Track, prev, next *model.Track, rng *rand.Rand) ([]model.Track, bool) {
+	remaining := make([]model.Track, len(tracks))
+	copy(remaining, tracks)
+
+	counts := make(map[string]int)
+	for _, t := range remaining {
+		if len(t.ArtistsId) > 0 {
+			counts[t.ArtistsId[0]]++
+		}
+	}
+	// 末尾の曲と同じアーティストの曲は、最後に残らないよう先に置く
+	if next != nil && len(next.ArtistsId) > 0 {
+		if _, ok := counts[next.ArtistsId[0]]; ok {
+			counts[next.ArtistsId[0]]++
+		}
+	}
+
+	ordered := make([]model.Track, 0, len(tracks))
+	for len(remaining) > 0 {
+		before := prev
+		if len(ordered) > 0 {
+			before = &ordered[len(ordered)-1]
+		}
+
+		best, bestCount, ties := -1, -1, 0
+		for i, t := range remaining {
+			if before != nil && sharesArtist(*before, t) {
+				continue
+			}
+			c := 0
+			if len(t.ArtistsId) > 0 {
+				c = counts[t.ArtistsId[0]]
+			}
+			switch {
+			case c > bestCount:
+				best, bestCount, ties = i, c, 1
+			case c == bestCount:
+				ties++
+				if rng.Intn(ties) == 0 {
+					best = i
+				}
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+
+		t := remaining[best]
+		if len(t.ArtistsId) > 0 {
+			counts[t.ArtistsId[0]]--
+		}
+		ordered = append(ordered, t)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	if next == nil || len(ordered) == 0 || !sharesArtist(ordered[len(ordered)-1], *next) {
+		return ordered, true
+	}
+	return moveLastBeforeNext(ordered, prev, next)
+}
+
+// moveLastBeforeNext は最後の曲が next と隣り合う場合に、最後の曲を途中の隣り合わない位置に移す。
+// 移せる位置がない場合は false を返す。
+func moveLastBeforeNext(ordered []model.Track, prev, next *model.Track) ([]model.Track, bool) {
+	last := ordered[len(ordered)-1]
+	rest := ordered[:len(ordered)-1]
+	if len(rest) == 0 || sharesArtist(rest[len(rest)-1], *next) {
+		return nil, false
+	}
+	for i := range rest {
+		if sharesArtist(last, rest[i]) {
+			continue
+		}
+		if i == 0 && prev != nil && sharesArtist(*prev, last) {
+			continue
+		}
+		if i > 0 && sharesArtist(rest[i-1], last) {
+			continue
+		}
+		moved := make([]model.Track, 0, len(ordered))
+		moved = append(moved, rest[:i]...)
+		moved = append(moved, last)
+		moved = append(moved, rest[i:]...)
+		return moved, true
+	}
+	return nil, false
+}
+
+// sharesArtist は2曲に共通のアーティストがいるかを返す
+func sharesArtist(a, b model.Track) bool {
+	for _, x := range a.ArtistsId {
+		for _, y := range b.ArtistsId {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
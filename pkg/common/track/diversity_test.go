@@ -0,0 +1,97 @@
+package track
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// アーティストの多様性の制約のテスト
+// =============================================================================
+// maxTracksPerArtist / noConsecutiveSameArtist が指定された場合に、
+// Selector が以下を満たすことをテストする:
+// 1. 1アーティストあたりの曲数が上限以下になる
+// 2. 同じアーティストの曲が隣り合わない
+// 3. 制約を満たせない場合は組み合わせなしと判定される
+// =============================================================================
+
+// artistTracks は artistCount 人のアーティストが perArtist 曲ずつ持つ、
+// すべて1分のトラックを生成するテスト用ヘルパー
+func artistTracks(artistCount, perArtist int) []model.Track {
+	var tracks []model.Track
+	for a := 0; a < artistCount; a++ {
+		for i := 0; i < perArtist; i++ {
+			tracks = append(tracks, model.Track{
+				Uri:        fmt.Sprintf("artist%d-track%d", a, i),
+				DurationMs: 60000,
+				ArtistsId:  []string{fmt.Sprintf("artist%d", a)},
+			})
+		}
+	}
+	return tracks
+}
+
+// TestSelector_MaxTracksPerArtist は、1アーティストあたりの曲数が制限されることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1アーティスト20曲 + 4アーティスト各3曲（すべて1分）
+//   - 要求: 12分、maxTracksPerArtist=3
+//   - 期待結果: 成功、どのアーティストも3曲以下
+func TestSelector_MaxTracksPerArtist(t *testing.T) {
+	pool := append(artistTracks(1, 20), artistTracks(5, 3)[3:]...)
+
+	result, err := NewSelector(Options{Seed: 1, MaxTracksPerArtist: 3}).Select(context.Background(), pool, 12*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, track := range result.Tracks {
+		counts[track.ArtistsId[0]]++
+	}
+	for artist, c := range counts {
+		if c > 3 {
+			t.Errorf("artist %s has %d tracks, expected at most 3", artist, c)
+		}
+	}
+}
+
+// TestSelector_NoConsecutiveSameArtist は、同じアーティストの曲が隣り合わないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3アーティスト各5曲（すべて1分）
+//   - 要求: 9分、noConsecutiveSameArtist=true
+//   - 期待結果: 成功、隣り合う曲のアーティストがすべて異なる
+func TestSelector_NoConsecutiveSameArtist(t *testing.T) {
+	pool := artistTracks(3, 5)
+
+	for seed := int64(0); seed < 10; seed++ {
+		result, err := NewSelector(Options{Seed: seed, NoConsecutiveSameArtist: true}).Select(context.Background(), pool, 9*MillisecondsPerMinute)
+		if err != nil {
+			t.Fatalf("Select() unexpected error: %v", err)
+		}
+		for i := 1; i < len(result.Tracks); i++ {
+			if sharesArtist(result.Tracks[i-1], result.Tracks[i]) {
+				t.Fatalf("seed %d: tracks %d and %d share an artist", seed, i-1, i)
+			}
+		}
+	}
+}
+
+// TestOrderNoConsecutiveArtist_Impossible は、1アーティストの曲が半数を超えていて
+// 隣り合わせずに並べられない場合に false が返ることをテストする。
+//
+// テストシナリオ:
+//   - 入力: artist0 の曲3つ + artist1 の曲1つ
+//   - 期待結果: false
+func TestOrderNoConsecutiveArtist_Impossible(t *testing.T) {
+	tracks := append(artistTracks(1, 3), artistTracks(2, 1)[1:]...)
+
+	if _, ok := orderNoConsecutiveArtist(tracks, nil, nil, rand.New(rand.NewSource(1))); ok {
+		t.Error("Expected ordering to be impossible")
+	}
+}
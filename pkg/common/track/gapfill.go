@@ -0,0 +1,149 @@
+package track
+
+import (
+	"context"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+const (
+	// 隙間を埋められなかった場合に、候補プールの曲を減らして探し直す回数の上限
+	maxGapFillAttempts = 8
+
+	// 隙間埋めに使う曲数の上限
+	maxGapFillTracks = 3
+
+	// 2曲以上で隙間を埋める場合の合計再生時間の上限（指定時間に対する割合）。
+	// 候補プールが大きく足りない場合に、プレイリストの大半をカタログの曲にしないようにする。
+	maxGapFillRatio = 0.25
+)
+
+// GapFillFunc は個人の候補プールだけでは指定時間に合わない場合に、
+// 最後の隙間を埋める候補プールを優先順に返す（例: ユーザーの国のISRCの曲、カタログ全体）。
+// 必要になったときだけ呼ばれる。
+type GapFillFunc func() ([][]model.Track, error)
+
+// fillGap は候補プールの曲をできるだけ多く使い、残りの隙間を GapFill の候補プールから優先順に探す。
+// 候補プールの曲は「GapFill の最も短い曲が入る余地」を残した上限に最も近くなるように選び、
+// 隙間を埋められなければ候補プールの曲の合計を減らして探し直す。
+// 隙間を埋めるのは最後の maxGapFillTracks 曲までで、2曲以上なら合計を指定時間の maxGapFillRatio 以下にする。
+// 隙間埋めの曲も含めて1アーティストあたりの曲数を制限し、固定曲とあわせて並べ直す。
+// 隙間を埋めた曲には Origin に model.TrackOriginCatalog を付ける。
+// 隙間を埋められない場合は元のエラーを返す。
+// 選んだ曲（固定曲を配置して並べたもの）、探索の試行回数、隙間を埋めた曲数を返す。
+func (s *Selector) fillGap(ctx context.Context, pool []model.Track, w window, cause error) ([]model.Track, int, int, error) {
+	if approximationCause(cause) == "" {
+		return nil, 0, 0, cause
+	}
+
+	rawPools, err := s.opts.GapFill()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	// 候補プールと同じ曲、除外リストの曲、長さの範囲外の曲は隙間埋めに使わない
+	used := make(map[string]bool, len(pool))
+	for _, t := range pool {
+		used[trackKey(t)] = true
+	}
+	gapPools := make([][]model.Track, 0, len(rawPools))
+	shortestMs, longestMs := 0, 0
+	for _, raw := range rawPools {
+		candidates := make([]model.Track, 0, len(raw))
+		for _, t := range raw {
+			if !used[trackKey(t)] {
+				candidates = append(candidates, t)
+			}
+		}
+		candidates, _ = excludeBlocked(candidates, s.opts.Blocklist)
+		candidates, _ = filterByTrackLength(candidates, s.opts.MinTrackMs, s.opts.MaxTrackMs)
+		for _, t := range candidates {
+			if t.DurationMs > 0 && (shortestMs == 0 || t.DurationMs < shortestMs) {
+				shortestMs = t.DurationMs
+			}
+			longestMs = max(longestMs, t.DurationMs)
+		}
+		gapPools = append(gapPools, candidates)
+	}
+	if shortestMs == 0 {
+		return nil, 0, 0, cause
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	// 1曲で埋める場合は長さを問わず、2曲以上で埋める場合は指定時間の maxGapFillRatio まで
+	maxFillMs := int(float64(w.target) * maxGapFillRatio)
+
+	attempts := 0
+	bulkHi := w.hi - shortestMs
+	for i := 0; i < maxGapFillAttempts && bulkHi >= 0 && ctx.Err() == nil; i++ {
+		bulk, n, _, err := s.solveAvoidingRecent(ctx, pool, window{target: bulkHi, lo: 0, hi: bulkHi}, false)
+		attempts += n
+		if err != nil {
+			break
+		}
+		bulkMs := 0
+		for _, t := range bulk {
+			bulkMs += t.DurationMs
+		}
+		// 候補プールの曲を減らすほど隙間は大きくなるため、埋められる大きさを超えたら諦める
+		if w.lo-bulkMs > max(maxFillMs, longestMs) {
+			break
+		}
+
+		// 隙間埋めの曲数の制限には、候補プールから選んだ曲も数える
+		fillSelector := *s
+		fillSelector.opts.reserved = append(append([]model.Track{}, s.opts.reserved...), bulk...)
+
+		for _, gapPool := range gapPools {
+			fill, n, _, err := fillSelector.solveAvoidingRecent(ctx, gapPool, w.shift(bulkMs), false)
+			attempts += n
+			if err != nil {
+				continue
+			}
+			fillMs := 0
+			for _, t := range fill {
+				fillMs += t.DurationMs
+			}
+			if len(fill) > maxGapFillTracks || (len(fill) > 1 && fillMs > maxFillMs) {
+				continue
+			}
+
+			tracks := make([]model.Track, 0, len(bulk)+len(fill))
+			tracks = append(tracks, bulk...)
+			for _, t := range fill {
+				t.Origin = model.TrackOriginCatalog
+				tracks = append(tracks, t)
+			}
+			arranged, ok := s.arrange(tracks)
+			if !ok {
+				continue
+			}
+			return arranged, attempts, len(fill), nil
+		}
+
+		// 候補プールの曲の合計を1つ下の到達可能な値まで減らして探し直す
+		bulkHi = bulkMs - 1
+	}
+
+	return nil, attempts, 0, cause
+}
+
+// markOrigins は Origin が付いていない曲に出自を付ける。固定曲には model.TrackOriginPinned を付ける。
+func markOrigins(tracks []model.Track, pins []Pin, origin string) {
+	pinned := make(map[string]bool, len(pins))
+	for _, p := range pins {
+		pinned[trackKey(p.Track)] = true
+	}
+	for i := range tracks {
+		if tracks[i].Origin != "" {
+			continue
+		}
+		if pinned[trackKey(tracks[i])] {
+			tracks[i].Origin = model.TrackOriginPinned
+		} else {
+			tracks[i].Origin = origin
+		}
+	}
+}
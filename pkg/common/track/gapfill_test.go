@@ -0,0 +1,183 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// カタログによる隙間埋め（fillFromCatalog）のテスト
+// =============================================================================
+// Options.GapFill が指定された場合に、Selector が以下を満たすことをテストする:
+// 1. 候補プールだけで合う場合は GapFill を使わない
+// 2. 合わない場合は候補プールの曲を中心に、最後の隙間だけ GapFill の曲で埋める
+// 3. GapFill の候補プールは優先順に使う
+// 4. すべての曲に出自を付ける
+// =============================================================================
+
+// gapPools は GapFill に渡す候補プールを返すテスト用ヘルパー
+func gapPools(pools ...[]model.Track) GapFillFunc {
+	return func() ([][]model.Track, error) {
+		return pools, nil
+	}
+}
+
+// TestSelector_GapFill_NotNeeded は、候補プールだけで合う場合に GapFill を呼ばないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲10曲
+//   - 要求: 5分
+//   - 期待結果: 成功、gap_filled = 0、すべて origin = "favorites"
+func TestSelector_GapFill_NotNeeded(t *testing.T) {
+	called := false
+	gapFill := func() ([][]model.Track, error) {
+		called = true
+		return nil, nil
+	}
+
+	result, err := NewSelector(Options{Seed: 1, GapFill: gapFill, Origin: SegmentSourceFavorites}).Select(context.Background(), minuteTracks("fav", 10), 5*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if called {
+		t.Error("GapFill should not be called")
+	}
+	for _, track := range result.Tracks {
+		if track.Origin != SegmentSourceFavorites {
+			t.Errorf("Expected origin favorites, got %q", track.Origin)
+		}
+	}
+}
+
+// TestSelector_GapFill_Catalog は、候補プールで埋まらない隙間をカタログの曲で埋めることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲（合計8分か12分）、カタログに2分30秒と7分の曲
+//   - 要求: 10分30秒（±15秒）
+//   - 期待結果: 成功、4分の曲2曲 + カタログの2分30秒の曲、gap_filled = 1
+func TestSelector_GapFill_Catalog(t *testing.T) {
+	catalog := []model.Track{
+		{Uri: "catalog1", DurationMs: 150000},
+		{Uri: "catalog2", DurationMs: 420000},
+	}
+
+	result, err := NewSelector(Options{Seed: 1, GapFill: gapPools(catalog), Origin: SegmentSourceFavorites}).Select(context.Background(), fourMinuteTracks(), 630000)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.TotalMs != 630000 {
+		t.Errorf("Expected total 630000ms, got %d", result.TotalMs)
+	}
+	if result.Diagnostics.GapFilled != 1 {
+		t.Errorf("Expected 1 gap-filled track, got %d", result.Diagnostics.GapFilled)
+	}
+	for _, track := range result.Tracks {
+		want := SegmentSourceFavorites
+		if track.Uri == "catalog1" {
+			want = model.TrackOriginCatalog
+		}
+		if track.Origin != want {
+			t.Errorf("Track %s: expected origin %q, got %q", track.Uri, want, track.Origin)
+		}
+	}
+}
+
+// TestSelector_GapFill_Preferred は、優先する候補プールで埋まればそちらを使うことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲、優先プールに2分30秒の曲、全体プールに同じ長さの別の曲
+//   - 要求: 10分30秒
+//   - 期待結果: 優先プールの曲が選ばれる
+func TestSelector_GapFill_Preferred(t *testing.T) {
+	preferred := []model.Track{{Uri: "jp", DurationMs: 150000}}
+	all := []model.Track{{Uri: "us", DurationMs: 150000}, {Uri: "jp", DurationMs: 150000}}
+
+	result, err := NewSelector(Options{Seed: 1, GapFill: gapPools(preferred, all)}).Select(context.Background(), fourMinuteTracks(), 630000)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	for _, track := range result.Tracks {
+		if track.Uri == "us" {
+			t.Error("Track from the fallback pool was selected")
+		}
+	}
+}
+
+// TestSelector_GapFill_Fails は、隙間を埋められない場合に元のエラーを返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 4分の曲3曲、カタログに7分の曲のみ
+//   - 要求: 10分30秒
+//   - 期待結果: ErrTimeoutCreatePlaylist
+func TestSelector_GapFill_Fails(t *testing.T) {
+	catalog := []model.Track{{Uri: "catalog", DurationMs: 420000}}
+
+	_, err := NewSelector(Options{Seed: 1, GapFill: gapPools(catalog)}).Select(context.Background(), fourMinuteTracks(), 630000)
+
+	if !errors.Is(err, model.ErrTimeoutCreatePlaylist) {
+		t.Errorf("Expected ErrTimeoutCreatePlaylist, got %v", err)
+	}
+}
+
+// TestSelector_GapFill_OnlyFinalGap は、候補プールが大きく足りない場合に
+// 足りない分をすべてカタログの曲で埋めないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分の曲10曲（合計10分）、カタログに1分の曲100曲
+//   - 要求: 60分
+//   - 期待結果: ErrNotEnoughTracks（50分をカタログの曲で埋めない）
+func TestSelector_GapFill_OnlyFinalGap(t *testing.T) {
+	catalog := minuteTracks("catalog", 100)
+
+	_, err := NewSelector(Options{Seed: 1, GapFill: gapPools(catalog)}).Select(context.Background(), minuteTracks("fav", 10), 60*MillisecondsPerMinute)
+
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Errorf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+}
+
+// TestSelector_GapFill_Diversity は、隙間埋めの曲も含めてアーティストの多様性の制約を満たすことをテストする。
+//
+// テストシナリオ:
+//   - 入力: artist0 の4分の曲2曲と artist1 の4分の曲1曲、
+//     カタログに artist0 と artist2 の2分30秒の曲
+//   - 要求: 10分30秒、maxTracksPerArtist=2、noConsecutiveSameArtist=true
+//   - 期待結果: 成功、隙間埋めの曲を含めてどのアーティストも2曲以下、隣り合う曲のアーティストがすべて異なる
+func TestSelector_GapFill_Diversity(t *testing.T) {
+	pool := []model.Track{
+		{Uri: "fav0a", DurationMs: 240000, ArtistsId: []string{"artist0"}},
+		{Uri: "fav0b", DurationMs: 240000, ArtistsId: []string{"artist0"}},
+		{Uri: "fav1", DurationMs: 240000, ArtistsId: []string{"artist1"}},
+	}
+	catalog := []model.Track{
+		{Uri: "catalog0", DurationMs: 150000, ArtistsId: []string{"artist0"}},
+		{Uri: "catalog2", DurationMs: 150000, ArtistsId: []string{"artist2"}},
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		opts := Options{Seed: seed, GapFill: gapPools(catalog), MaxTracksPerArtist: 2, NoConsecutiveSameArtist: true}
+		result, err := NewSelector(opts).Select(context.Background(), pool, 630000)
+		if err != nil {
+			t.Fatalf("seed %d: Select() unexpected error: %v", seed, err)
+		}
+
+		counts := make(map[string]int)
+		for i, track := range result.Tracks {
+			counts[track.ArtistsId[0]]++
+			if i > 0 && sharesArtist(result.Tracks[i-1], track) {
+				t.Errorf("seed %d: tracks %d and %d share an artist", seed, i-1, i)
+			}
+		}
+		for artist, c := range counts {
+			if c > 2 {
+				t.Errorf("seed %d: artist %s has %d tracks, expected at most 2", seed, artist, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package track
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// 選曲元ごとの再生時間が配分より短くてよい割合（配分の10%、最小は AllowanceMs）
+const mixShareToleranceRatio = 0.1
+
+// 最後の選曲元で指定時間に合わせられなかった場合に、シードを変えて選び直す回数の上限
+const maxMixAttempts = 4
+
+// MixWeights は選曲元ごとの再生時間の配分（%）。合計は100にする。
+type MixWeights struct {
+	Favorites       int `json:"favorites" binding:"min=0,max=100"`
+	FollowedArtists int `json:"followedArtists" binding:"min=0,max=100"`
+	Catalog         int `json:"catalog" binding:"min=0,max=100"`
+}
+
+// MixParams は複数の選曲元を配分どおりに混ぜるプレイリスト作成リクエストの指定。
+// 例: {"weights": {"favorites": 60, "followedArtists": 30, "catalog": 10}, "artistIds": [...]}
+type MixParams struct {
+	Weights MixWeights `json:"weights"`
+	// ArtistIds は followedArtists の選曲に使うアーティスト（フォロー中のアーティスト）
+	ArtistIds []string `json:"artistIds"`
+}
+
+// MixPart は1つの選曲元とその配分
+type MixPart struct {
+	Segment
+	Weight int
+}
+
+// Parts は配分が0より大きい選曲元の一覧を返す。
+// 配分の合計が100でない場合、followedArtists に artistIds がない場合は model.ErrInvalidRequest を返す。
+func (p MixParams) Parts() ([]MixPart, error) {
+	w := p.Weights
+	if total := w.Favorites + w.FollowedArtists + w.Catalog; total != 100 {
+		return nil, model.WithDetails(model.ErrInvalidRequest, map[string]interface{}{
+			"reason": "weights_must_sum_to_100",
+			"total":  total,
+		})
+	}
+	if w.FollowedArtists > 0 && len(p.ArtistIds) == 0 {
+		return nil, model.WithDetails(model.ErrInvalidRequest, map[string]interface{}{
+			"reason": "artist_ids_required",
+		})
+	}
+
+	var parts []MixPart
+	if w.Favorites > 0 {
+		parts = append(parts, MixPart{Segment: Segment{Source: SegmentSourceFavorites}, Weight: w.Favorites})
+	}
+	if w.FollowedArtists > 0 {
+		parts = append(parts, MixPart{Segment: Segment{Source: SegmentSourceArtists, ArtistIds: p.ArtistIds}, Weight: w.FollowedArtists})
+	}
+	if w.Catalog > 0 {
+		parts = append(parts, MixPart{Segment: Segment{Source: SegmentSourceCatalog}, Weight: w.Catalog})
+	}
+	return parts, nil
+}
+
+// FillMix は選曲元ごとの再生時間が配分に近くなるように選曲し、混ぜて並べた曲と選曲元ごとの内訳を返す。
+//
+// 最後の選曲元以外は配分を超えない範囲で配分に近くなるように選び（足りなければ選べるだけ選ぶ）、
+// 最後の選曲元で残り時間を指定時間に合わせる。最後の選曲元には候補の再生時間が最も多いものを使う。
+// 合わせられなければシードを変えて maxMixAttempts 回まで選び直す。
+// 各曲の Origin には選曲元を付ける。固定曲は全体に対して配置する。
+// 結果の Diagnostics は選曲元ごとの診断情報を足し合わせたもの。
+// CrossfadeMs が指定されていれば、曲の重なりを引いた実際の再生時間で合わせる。
+func FillMix(ctx context.Context, parts []MixPart, targetMs int, opts Options, poolFunc PoolFunc) (*Result, []model.MixShare, error) {
+	if opts.CrossfadeMs > 0 {
+		return fillMixCrossfaded(ctx, parts, targetMs, opts, poolFunc)
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	pinnedMs := 0
+	for _, p := range opts.Pins {
+		pinnedMs += p.Track.DurationMs
+	}
+	remainingMs := targetMs - pinnedMs
+	if remainingMs < 0 {
+		return nil, nil, model.WithDetails(model.ErrInvalidPinnedTracks, map[string]interface{}{
+			"reason":      "too_long",
+			"pinned_ms":   pinnedMs,
+			"required_ms": targetMs,
+		})
+	}
+
+	// 選曲元ごとの候補プールを取得し、複数の選曲元にある曲は先の選曲元だけに含める。
+	// 固定曲はどの候補プールにも含めない。
+	pools := make([][]model.Track, len(parts))
+	seen := make(map[string]bool)
+	for _, p := range opts.Pins {
+		seen[trackKey(p.Track)] = true
+	}
+	closing := 0
+	closingMs := -1
+	for i, part := range parts {
+		pool, err := poolFunc(part.Segment)
+		if err != nil {
+			return nil, nil, err
+		}
+		poolMs := 0
+		for _, t := range pool {
+			if seen[trackKey(t)] {
+				continue
+			}
+			seen[trackKey(t)] = true
+			pools[i] = append(pools[i], t)
+			poolMs += t.DurationMs
+		}
+		if poolMs > closingMs {
+			closing, closingMs = i, poolMs
+		}
+	}
+
+	// 選曲元ごとの選曲では固定曲と隙間埋めを扱わない
+	partOpts := opts
+	partOpts.Pins = nil
+	partOpts.GapFill = nil
+
+	var lastErr error
+	for attempt := 0; attempt < maxMixAttempts; attempt++ {
+		if attempt > 0 && ctx.Err() != nil {
+			break
+		}
+
+		var tracks []model.Track
+		var diag Diagnostics
+		shares := make([]model.MixShare, len(parts))
+		usedMs := 0
+		var approx *Approximation
+		failed := false
+
+		for _, i := range mixOrder(len(parts), closing) {
+			part := parts[i]
+			shareMs := remainingMs * part.Weight / 100
+
+			o := partOpts
+			o.Seed = rng.Int63n(MaxSeed)
+			// 固定曲と先に選んだ選曲元の曲も1アーティストあたりの曲数に数える
+			o.reserved = append(pinTracks(opts.Pins), tracks...)
+			o.Source = opts.Source + "/" + part.Source
+			if i == closing {
+				// 残り時間を全体の許容誤差で合わせる
+				shareMs = remainingMs - usedMs
+				tolerance := opts.Policy(targetMs).ToleranceMs
+				o.ToleranceMs = &tolerance
+			} else {
+				tolerance := int(float64(shareMs) * mixShareToleranceRatio)
+				if tolerance < AllowanceMs {
+					tolerance = AllowanceMs
+				}
+				o.ToleranceMs = &tolerance
+				o.Mode = FitModeUnderOnly
+				o.AllowApproximate = true
+			}
+
+			if shareMs <= 0 {
+				shares[i] = model.MixShare{Source: part.Source, Weight: part.Weight}
+				continue
+			}
+			result, err := NewSelector(o).Select(ctx, pools[i], shareMs)
+			if err != nil && i != closing && approximationCause(err) != "" {
+				// 1曲も選べない選曲元は0曲として、残りを最後の選曲元で埋める
+				result, err = &Result{}, nil
+			}
+			if err != nil {
+				lastErr = err
+				failed = true
+				break
+			}
+			if i == closing {
+				approx = result.Approximation
+			}
+
+			for _, t := range result.Tracks {
+				t.Origin = part.Source
+				tracks = append(tracks, t)
+			}
+			addDiagnostics(&diag, result.Diagnostics)
+			usedMs += result.TotalMs
+			shares[i] = model.MixShare{
+				Source:     part.Source,
+				Weight:     part.Weight,
+				TargetMs:   shareMs,
+				ActualMs:   result.TotalMs,
+				TrackCount: len(result.Tracks),
+			}
+		}
+		if failed {
+			continue
+		}
+
+		// 選曲元が偏って並ばないように混ぜる
+		rng.Shuffle(len(tracks), func(i, j int) {
+			tracks[i], tracks[j] = tracks[j], tracks[i]
+		})
+		tracks, ok := arrangePins(tracks, opts.Pins, opts.NoConsecutiveSameArtist, rng)
+		if !ok {
+			lastErr = model.ErrTimeoutCreatePlaylist
+			continue
+		}
+		markOrigins(tracks, opts.Pins, "")
+
+		result := &Result{Tracks: tracks, Diagnostics: diag}
+		for _, t := range tracks {
+			result.TotalMs += t.DurationMs
+		}
+		result.Diagnostics.RemainderMs = targetMs - result.TotalMs
+		if approx != nil {
+			approx.RequiredMs = targetMs
+			approx.TotalMs = result.TotalMs
+			result.Approximation = approx
+		}
+		return result, shares, nil
+	}
+
+	return nil, nil, lastErr
+}
+
+// mixOrder は選曲する順番を返す。残り時間を合わせる closing は最後にする。
+func mixOrder(n, closing int) []int {
+	order := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if i != closing {
+			order = append(order, i)
+		}
+	}
+	return append(order, closing)
+}
@@ -0,0 +1,179 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 複数の選曲元を配分で混ぜる選曲のテスト
+// =============================================================================
+// MixParams.Parts と FillMix が以下を満たすことをテストする:
+// 1. 配分の合計が100でない場合はエラーにする
+// 2. 選曲元ごとの再生時間が配分に近く、合計が指定時間に合う
+// 3. 各曲に選曲元の出自を付ける
+// 4. 複数の選曲元にある曲は重複して選ばない
+// =============================================================================
+
+// mixPoolFunc は選曲元ごとに決まった候補プールを返すテスト用ヘルパー
+func mixPoolFunc(pools map[string][]model.Track) PoolFunc {
+	return func(seg Segment) ([]model.Track, error) {
+		return pools[seg.Source], nil
+	}
+}
+
+// TestMixParams_Parts は配分の検証をテストする。
+//
+// テストシナリオ:
+//   - 合計90 → ErrInvalidRequest
+//   - followedArtists > 0 で artistIds なし → ErrInvalidRequest
+//   - {favorites: 60, catalog: 40} → 2つの選曲元
+func TestMixParams_Parts(t *testing.T) {
+	_, err := MixParams{Weights: MixWeights{Favorites: 60, Catalog: 30}}.Parts()
+	if !errors.Is(err, model.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for total 90, got %v", err)
+	}
+
+	_, err = MixParams{Weights: MixWeights{Favorites: 50, FollowedArtists: 50}}.Parts()
+	if !errors.Is(err, model.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest without artistIds, got %v", err)
+	}
+
+	parts, err := MixParams{Weights: MixWeights{Favorites: 60, Catalog: 40}}.Parts()
+	if err != nil {
+		t.Fatalf("Parts() unexpected error: %v", err)
+	}
+	if len(parts) != 2 || parts[0].Source != SegmentSourceFavorites || parts[1].Source != SegmentSourceCatalog {
+		t.Errorf("Unexpected parts: %+v", parts)
+	}
+}
+
+// TestFillMix_Weights は、配分どおりに選曲元を混ぜることをテストする。
+//
+// テストシナリオ:
+//   - 入力: お気に入り1分の曲20曲、カタログ1分の曲50曲
+//   - 要求: 10分、favorites 60 / catalog 40
+//   - 期待結果: 合計10分、お気に入り6分・カタログ4分、出自が選曲元と一致
+func TestFillMix_Weights(t *testing.T) {
+	pools := map[string][]model.Track{
+		SegmentSourceFavorites: minuteTracks("fav", 20),
+		SegmentSourceCatalog:   minuteTracks("cat", 50),
+	}
+	parts, _ := MixParams{Weights: MixWeights{Favorites: 60, Catalog: 40}}.Parts()
+
+	result, shares, err := FillMix(context.Background(), parts, 10*MillisecondsPerMinute, Options{Seed: 1}, mixPoolFunc(pools))
+
+	if err != nil {
+		t.Fatalf("FillMix() unexpected error: %v", err)
+	}
+	if result.TotalMs != 10*MillisecondsPerMinute {
+		t.Errorf("Expected total 600000ms, got %d", result.TotalMs)
+	}
+	if shares[0].ActualMs != 6*MillisecondsPerMinute || shares[1].ActualMs != 4*MillisecondsPerMinute {
+		t.Errorf("Unexpected shares: %+v", shares)
+	}
+	for _, track := range result.Tracks {
+		want := SegmentSourceFavorites
+		if track.Uri[:3] == "cat" {
+			want = SegmentSourceCatalog
+		}
+		if track.Origin != want {
+			t.Errorf("Track %s: expected origin %q, got %q", track.Uri, want, track.Origin)
+		}
+	}
+}
+
+// TestFillMix_ShortPersonalPool は、お気に入りが配分に足りない場合に
+// 残りを最後の選曲元で埋めることをテストする。
+//
+// テストシナリオ:
+//   - 入力: お気に入り1分の曲3曲、カタログ1分の曲50曲
+//   - 要求: 10分、favorites 60 / catalog 40
+//   - 期待結果: 合計10分、お気に入り3曲すべて、カタログ7分
+func TestFillMix_ShortPersonalPool(t *testing.T) {
+	pools := map[string][]model.Track{
+		SegmentSourceFavorites: minuteTracks("fav", 3),
+		SegmentSourceCatalog:   minuteTracks("cat", 50),
+	}
+	parts, _ := MixParams{Weights: MixWeights{Favorites: 60, Catalog: 40}}.Parts()
+
+	result, shares, err := FillMix(context.Background(), parts, 10*MillisecondsPerMinute, Options{Seed: 1}, mixPoolFunc(pools))
+
+	if err != nil {
+		t.Fatalf("FillMix() unexpected error: %v", err)
+	}
+	if result.TotalMs != 10*MillisecondsPerMinute {
+		t.Errorf("Expected total 600000ms, got %d", result.TotalMs)
+	}
+	if shares[0].TrackCount != 3 || shares[1].ActualMs != 7*MillisecondsPerMinute {
+		t.Errorf("Unexpected shares: %+v", shares)
+	}
+}
+
+// TestFillMix_NoDuplicates は、複数の選曲元にある曲を重複して選ばないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: お気に入りとカタログが同じ1分の曲10曲
+//   - 要求: 10分、favorites 50 / catalog 50
+//   - 期待結果: 10曲すべて異なる
+func TestFillMix_NoDuplicates(t *testing.T) {
+	shared := minuteTracks("track", 10)
+	pools := map[string][]model.Track{
+		SegmentSourceFavorites: shared,
+		SegmentSourceCatalog:   shared,
+	}
+	parts, _ := MixParams{Weights: MixWeights{Favorites: 50, Catalog: 50}}.Parts()
+
+	result, _, err := FillMix(context.Background(), parts, 10*MillisecondsPerMinute, Options{Seed: 1}, mixPoolFunc(pools))
+
+	if err != nil {
+		t.Fatalf("FillMix() unexpected error: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, track := range result.Tracks {
+		if seen[track.Uri] {
+			t.Errorf("Track %s selected twice", track.Uri)
+		}
+		seen[track.Uri] = true
+	}
+}
+
+// TestFillMix_Diagnostics は、選曲元ごとの診断情報を足し合わせて返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: お気に入り1分の曲20曲、カタログ1分の曲50曲
+//   - 要求: 10分、favorites 60 / catalog 40
+//   - 期待結果: 候補プールは70曲（70分）、曲の長さの分布も70曲、残りは0
+func TestFillMix_Diagnostics(t *testing.T) {
+	pools := map[string][]model.Track{
+		SegmentSourceFavorites: minuteTracks("fav", 20),
+		SegmentSourceCatalog:   minuteTracks("cat", 50),
+	}
+	parts, _ := MixParams{Weights: MixWeights{Favorites: 60, Catalog: 40}}.Parts()
+
+	result, _, err := FillMix(context.Background(), parts, 10*MillisecondsPerMinute, Options{Seed: 1, Debug: true}, mixPoolFunc(pools))
+
+	if err != nil {
+		t.Fatalf("FillMix() unexpected error: %v", err)
+	}
+	diag := result.Diagnostics
+	if diag.PoolSize != 70 || diag.PoolDurationMs != 70*MillisecondsPerMinute {
+		t.Errorf("Expected pool of 70 tracks (70 minutes), got %d tracks (%dms)", diag.PoolSize, diag.PoolDurationMs)
+	}
+	histogram := 0
+	for _, b := range diag.DurationHistogram {
+		histogram += b.Count
+	}
+	if histogram != 70 {
+		t.Errorf("Expected 70 tracks in histogram, got %d", histogram)
+	}
+	if diag.Attempts == 0 {
+		t.Error("Expected attempts to be counted")
+	}
+	if diag.RemainderMs != 0 {
+		t.Errorf("Expected remainder 0, got %d", diag.RemainderMs)
+	}
+}
@@ -0,0 +1,165 @@
+package track
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// MaxSeed はサーバー側で生成するシードの上限（2^53）
+// JavaScriptクライアントで数値として安全に扱える範囲に収める
+const MaxSeed = 1 << 53
+
+// Params はプレイリスト作成リクエストで共通の選曲パラメータ。
+// 各作成リクエストに埋め込んで使う。
+type Params struct {
+	// Seed は選曲に使う乱数シード。省略時はサーバー側で生成する。
+	// 同じ候補プールに同じシードを指定すると同じプレイリストが生成される。
+	Seed *int64 `json:"seed" binding:"omitempty,min=0"`
+
+	// ToleranceMs は指定時間に対する許容誤差（最大5分）。省略時は10分以上のみ15秒を許容する。
+	ToleranceMs *int `json:"toleranceMs" binding:"omitempty,min=0,max=300000"`
+
+	// Mode は誤差を許容する方向（exact / under-only / over-only）。省略時は前後どちらも許容する。
+	Mode FitMode `json:"mode" binding:"omitempty,oneof=exact under-only over-only"`
+
+	// MaxTracksPerArtist は1アーティストあたりの最大曲数。省略時は制限しない。
+	MaxTracksPerArtist int `json:"maxTracksPerArtist" binding:"omitempty,min=1"`
+
+	// NoConsecutiveSameArtist が true の場合、同じアーティストの曲を連続させない。
+	NoConsecutiveSameArtist bool `json:"noConsecutiveSameArtist"`
+
+	// MinTrackMs / MaxTrackMs は1曲あたりの再生時間の範囲。範囲外の曲は候補から外す。
+	MinTrackMs int `json:"minTrackMs" binding:"omitempty,min=1"`
+	MaxTrackMs int `json:"maxTrackMs" binding:"omitempty,min=1,gtefield=MinTrackMs"`
+
+	// Pinned は必ずプレイリストに含める曲。再生時間は指定時間から差し引かれる。
+	Pinned []PinnedTrack `json:"pinned" binding:"omitempty,max=20,dive"`
+
+	// AvoidRecent は直近 N 個のプレイリストで使った曲を避ける。
+	// FreshnessDays は直近 N 日以内のプレイリストで使った曲を避ける。
+	// 両方指定した場合は両方の条件に当てはまるプレイリストが対象。
+	// 候補が足りない場合は古いプレイリストの曲から順に避ける対象から外す。
+	AvoidRecent   int `json:"avoidRecent" binding:"omitempty,min=1,max=50"`
+	FreshnessDays int `json:"freshnessDays" binding:"omitempty,min=1,max=365"`
+
+	// AllowApproximate が true の場合、許容範囲内の組み合わせがなくてもエラーにせず、
+	// 指定時間に最も近い組み合わせでプレイリストを作成する。
+	AllowApproximate bool `json:"allowApproximate"`
+
+	// FillFromCatalog が true の場合、お気に入りや指定アーティストの曲だけでは指定時間に合わないときに、
+	// 最後の隙間を埋める曲をグローバルカタログから選ぶ（Spotifyのみ）。
+	FillFromCatalog bool `json:"fillFromCatalog"`
+
+	// CrossfadeSeconds はクライアントのクロスフェードの秒数（最大12秒）。
+	// 指定した場合、曲の重なりを引いた実際の再生時間を指定時間に合わせる。
+	CrossfadeSeconds int `json:"crossfadeSeconds" binding:"omitempty,min=0,max=12"`
+
+	// Debug が true の場合、選曲の診断情報をレスポンス（エラー時は details）に含める
+	Debug bool `json:"debug"`
+}
+
+// AvoidsRecent は最近使った曲を避ける指定があるかを返す
+func (p Params) AvoidsRecent() bool {
+	return p.AvoidRecent > 0 || p.FreshnessDays > 0
+}
+
+// PinnedUris は固定曲の Uri の一覧を返す
+func (p Params) PinnedUris() []string {
+	uris := make([]string, len(p.Pinned))
+	for i, pin := range p.Pinned {
+		uris[i] = pin.Uri
+	}
+	return uris
+}
+
+// Options はリクエストのパラメータから Selector の Options を組み立てる。
+// シードが省略されていれば新しく生成する。
+func (p Params) Options() Options {
+	var seed int64
+	if p.Seed != nil {
+		seed = *p.Seed
+	} else {
+		seed = NewSeed()
+	}
+	return Options{
+		Seed:        seed,
+		ToleranceMs: p.ToleranceMs,
+		Mode:        p.Mode,
+
+		MaxTracksPerArtist:      p.MaxTracksPerArtist,
+		NoConsecutiveSameArtist: p.NoConsecutiveSameArtist,
+
+		MinTrackMs: p.MinTrackMs,
+		MaxTrackMs: p.MaxTrackMs,
+
+		AllowApproximate: p.AllowApproximate,
+		CrossfadeMs:      p.CrossfadeSeconds * MillisecondsPerSecond,
+
+		Debug: p.Debug,
+	}
+}
+
+// NewSeed は新しい乱数シードを生成する
+func NewSeed() int64 {
+	return rand.Int63n(MaxSeed)
+}
+
+// Duration はプレイリストの再生時間の指定。
+// minute / durationMs / durationSeconds のいずれか1つを指定する。
+// 上限は MaxPlaylistDurationMs（24時間）をそれぞれの単位にしたもの。
+type Duration struct {
+	Minute          int `json:"minute" binding:"omitempty,min=1,max=1440"`
+	DurationMs      int `json:"durationMs" binding:"omitempty,min=1,max=86400000"`
+	DurationSeconds int `json:"durationSeconds" binding:"omitempty,min=1,max=86400"`
+}
+
+// TotalMs は指定された再生時間をミリ秒で返す。
+// 指定がない、複数指定されている、または範囲外の場合は model.ErrInvalidDuration を返す。
+func (d Duration) TotalMs() (int, error) {
+	specified := 0
+	ms := 0
+	if d.Minute > 0 {
+		specified++
+		ms = toMs(d.Minute, MillisecondsPerMinute)
+	}
+	if d.DurationSeconds > 0 {
+		specified++
+		ms = toMs(d.DurationSeconds, MillisecondsPerSecond)
+	}
+	if d.DurationMs > 0 {
+		specified++
+		ms = d.DurationMs
+	}
+
+	if specified != 1 || ms < MinPlaylistDurationMs || ms > MaxPlaylistDurationMs {
+		return 0, model.ErrInvalidDuration
+	}
+	return ms, nil
+}
+
+// toMs は value（単位は unitMs ミリ秒）をミリ秒にする。
+// 掛け算で桁あふれして範囲内の値にならないよう、上限を超える値は掛ける前に上限を超えるミリ秒にする。
+func toMs(value, unitMs int) int {
+	if value > MaxPlaylistDurationMs/unitMs {
+		return MaxPlaylistDurationMs + 1
+	}
+	return value * unitMs
+}
+
+// FormatDuration は再生時間をプレイリストのタイトル用の文字列にする。
+// 分単位ちょうどなら従来どおり "30min"、秒を含む場合は "7m30s" のように表す。
+func FormatDuration(ms int) string {
+	if ms%MillisecondsPerMinute == 0 {
+		return strconv.Itoa(ms/MillisecondsPerMinute) + "min"
+	}
+
+	minutes := ms / MillisecondsPerMinute
+	rest := ms % MillisecondsPerMinute
+	seconds := strconv.FormatFloat(float64(rest)/MillisecondsPerSecond, 'f', -1, 64) + "s"
+	if minutes == 0 {
+		return seconds
+	}
+	return strconv.Itoa(minutes) + "m" + seconds
+}
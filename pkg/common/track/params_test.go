@@ -0,0 +1,131 @@
+package track
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// Duration.TotalMs 関数のテスト
+// =============================================================================
+// TotalMs は minute / durationMs / durationSeconds のいずれか1つから
+// 再生時間をミリ秒で求める関数。
+// 以下のロジックをテストする:
+// 1. どの単位で指定してもミリ秒に変換される
+// 2. 指定なし、複数指定は ErrInvalidDuration になる
+// 3. 30秒未満、24時間超は ErrInvalidDuration になる（ミリ秒にすると桁あふれする値も含む）
+// =============================================================================
+
+// TestDuration_TotalMs は、再生時間の指定が正しく解釈されるかをテストする。
+func TestDuration_TotalMs(t *testing.T) {
+	tests := []struct {
+		name     string   // テストケースの説明
+		duration Duration // 入力
+		expected int      // 期待される再生時間（ms）
+		wantErr  bool     // エラーが期待されるか
+	}{
+		{
+			// 従来どおりの分指定
+			name:     "minute",
+			duration: Duration{Minute: 30},
+			expected: 1800000,
+		},
+		{
+			// 秒単位の指定（7分30秒）
+			name:     "seconds",
+			duration: Duration{DurationSeconds: 450},
+			expected: 450000,
+		},
+		{
+			// ミリ秒単位の指定
+			name:     "milliseconds",
+			duration: Duration{DurationMs: 450500},
+			expected: 450500,
+		},
+		{
+			// 何も指定されていない
+			name:     "not specified",
+			duration: Duration{},
+			wantErr:  true,
+		},
+		{
+			// 複数の単位が同時に指定されている
+			name:     "multiple fields",
+			duration: Duration{Minute: 7, DurationSeconds: 450},
+			wantErr:  true,
+		},
+		{
+			// 下限（30秒）未満
+			name:     "too short",
+			duration: Duration{DurationSeconds: 29},
+			wantErr:  true,
+		},
+		{
+			// 上限（24時間）超
+			name:     "too long",
+			duration: Duration{Minute: 24*60 + 1},
+			wantErr:  true,
+		},
+		{
+			// ミリ秒にすると桁あふれして範囲内（約68秒）になる分指定
+			name:     "minute overflow",
+			duration: Duration{Minute: 307445734561827},
+			wantErr:  true,
+		},
+		{
+			// ミリ秒にすると桁あふれして範囲内（約30秒）になる秒指定
+			name:     "seconds overflow",
+			duration: Duration{DurationSeconds: 18446744073709582},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.duration.TotalMs()
+			if tt.wantErr {
+				if !errors.Is(err, model.ErrInvalidDuration) {
+					t.Errorf("TotalMs() error = %v, expected ErrInvalidDuration", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TotalMs() unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("TotalMs() = %d, expected %d", result, tt.expected)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// FormatDuration 関数のテスト
+// =============================================================================
+
+// TestFormatDuration は、タイトル用の再生時間表記をテストする。
+//
+// テストケース:
+//   - 分単位ちょうど: 従来どおり "30min"
+//   - 秒を含む: "7m30s"
+//   - 1分未満: "45s"
+//   - ミリ秒を含む: "7m30.5s"
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		ms       int
+		expected string
+	}{
+		{1800000, "30min"},
+		{450000, "7m30s"},
+		{45000, "45s"},
+		{450500, "7m30.5s"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatDuration(tt.ms); result != tt.expected {
+			t.Errorf("FormatDuration(%d) = %q, expected %q", tt.ms, result, tt.expected)
+		}
+	}
+}
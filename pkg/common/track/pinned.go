@@ -0,0 +1,169 @@
+package track
+
+import (
+	"math/rand"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// PinPosition は固定曲をプレイリストのどこに置くか
+type PinPosition string
+
+const (
+	PinPositionAny   PinPosition = ""      // 任意の位置
+	PinPositionFirst PinPosition = "first" // 先頭
+	PinPositionLast  PinPosition = "last"  // 末尾（例: 仮眠タイマーの目覚ましの曲）
+)
+
+// PinnedTrack はリクエストで指定する、必ずプレイリストに含める曲
+type PinnedTrack struct {
+	// Uri は Spotify のトラックURI、または SoundCloud のトラックID
+	Uri      string      `json:"uri" binding:"required"`
+	Position PinPosition `json:"position" binding:"omitempty,oneof=first last"`
+}
+
+// Pin は再生時間などを解決済みの固定曲
+type Pin struct {
+	Track    model.Track
+	Position PinPosition
+}
+
+// ResolvePins はリクエストの固定曲と、プロバイダから取得した曲を対応付ける。
+// key は取得した曲からリクエストの Uri に対応する値を取り出す関数。
+// 見つからない曲がある場合は model.ErrInvalidPinnedTracks を返す。
+func ResolvePins(pinned []PinnedTrack, found []model.Track, key func(model.Track) string) ([]Pin, error) {
+	byKey := make(map[string]model.Track, len(found))
+	for _, t := range found {
+		byKey[key(t)] = t
+	}
+
+	pins := make([]Pin, 0, len(pinned))
+	var missing []string
+	seen := make(map[string]bool)
+	for _, p := range pinned {
+		t, ok := byKey[p.Uri]
+		if !ok || t.DurationMs <= 0 {
+			missing = append(missing, p.Uri)
+			continue
+		}
+		if seen[p.Uri] {
+			continue
+		}
+		seen[p.Uri] = true
+		pins = append(pins, Pin{Track: t, Position: p.Position})
+	}
+
+	if len(missing) > 0 {
+		return nil, model.WithDetails(model.ErrInvalidPinnedTracks, map[string]interface{}{
+			"reason":  "not_found",
+			"missing": missing,
+		})
+	}
+	return pins, nil
+}
+
+// excludePinned は候補プールから固定曲を除き、固定曲の合計再生時間とともに返す
+func excludePinned(pool []model.Track, pins []Pin) ([]model.Track, int) {
+	if len(pins) == 0 {
+		return pool, 0
+	}
+
+	pinnedMs := 0
+	pinned := make(map[string]bool, len(pins))
+	for _, p := range pins {
+		pinned[trackKey(p.Track)] = true
+		pinnedMs += p.Track.DurationMs
+	}
+
+	filtered := make([]model.Track, 0, len(pool))
+	for _, t := range pool {
+		if !pinned[trackKey(t)] {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, pinnedMs
+}
+
+// placePins は選曲結果に固定曲を指定された位置で挿入する。
+// first は先頭、last は末尾に指定順で並べ、位置指定のない曲はその間のランダムな位置に置く。
+func placePins(tracks []model.Track, pins []Pin, rng *rand.Rand) []model.Track {
+	if len(pins) == 0 {
+		return tracks
+	}
+
+	middle := make([]model.Track, len(tracks), len(tracks)+len(pins))
+	copy(middle, tracks)
+
+	var first, last []model.Track
+	for _, p := range pins {
+		switch p.Position {
+		case PinPositionFirst:
+			first = append(first, p.Track)
+		case PinPositionLast:
+			last = append(last, p.Track)
+		default:
+			i := rng.Intn(len(middle) + 1)
+			middle = append(middle, model.Track{})
+			copy(middle[i+1:], middle[i:])
+			middle[i] = p.Track
+		}
+	}
+
+	placed := make([]model.Track, 0, len(first)+len(middle)+len(last))
+	placed = append(placed, first...)
+	placed = append(placed, middle...)
+	placed = append(placed, last...)
+	return placed
+}
+
+// arrangePins は選曲結果に固定曲を配置する。
+// noConsecutive が true の場合は、位置指定のない固定曲も選曲結果と一緒に並べ替え、
+// 先頭・末尾の固定曲との境目も含めて同じアーティストの曲が隣り合わないようにする
+// （first / last の固定曲どうしは指定順のまま並べる）。並べられない場合は false を返す。
+func arrangePins(tracks []model.Track, pins []Pin, noConsecutive bool, rng *rand.Rand) ([]model.Track, bool) {
+	if !noConsecutive {
+		return placePins(tracks, pins, rng), true
+	}
+
+	middle := make([]model.Track, len(tracks), len(tracks)+len(pins))
+	copy(middle, tracks)
+
+	var first, last []model.Track
+	for _, p := range pins {
+		switch p.Position {
+		case PinPositionFirst:
+			first = append(first, p.Track)
+		case PinPositionLast:
+			last = append(last, p.Track)
+		default:
+			middle = append(middle, p.Track)
+		}
+	}
+
+	var prev, next *model.Track
+	if len(first) > 0 {
+		prev = &first[len(first)-1]
+	}
+	if len(last) > 0 {
+		next = &last[0]
+	}
+	ordered, ok := orderNoConsecutiveArtist(middle, prev, next, rng)
+	if !ok {
+		return nil, false
+	}
+
+	placed := make([]model.Track, 0, len(first)+len(ordered)+len(last))
+	placed = append(placed, first...)
+	placed = append(placed, ordered...)
+	placed = append(placed, last...)
+	return placed, true
+}
+
+// pinTracks は固定曲の曲の一覧を返す
+func pinTracks(pins []Pin) []model.Track {
+	tracks := make([]model.Track, len(pins))
+	for i, p := range pins {
+		tracks[i] = p.Track
+	}
+	return tracks
+}
@@ -0,0 +1,145 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 固定曲（pinned）のテスト
+// =============================================================================
+// 固定曲が指定された場合に、Selector が以下を満たすことをテストする:
+// 1. 固定曲の再生時間を差し引いた残りを候補プールから埋める
+// 2. 固定曲が指定された位置（first / last）に置かれる
+// 3. 固定曲だけで指定時間を超える場合は ErrInvalidPinnedTracks になる
+// 4. アーティストの多様性の制約に固定曲も含める
+// =============================================================================
+
+// TestSelector_PinnedLast は、仮眠タイマーの目覚ましの曲が末尾に置かれることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 2分の曲10曲（候補）、4分の目覚ましの曲（last で固定）
+//   - 要求: 20分
+//   - 期待結果: 成功、合計20分、末尾が目覚ましの曲、固定曲は1回だけ含まれる
+func TestSelector_PinnedLast(t *testing.T) {
+	alarm := model.Track{Uri: "alarm", DurationMs: 240000}
+	pool := []model.Track{alarm}
+	for i := 0; i < 10; i++ {
+		pool = append(pool, model.Track{Uri: fmt.Sprintf("track%d", i), DurationMs: 120000})
+	}
+
+	opts := Options{Seed: 1, Pins: []Pin{{Track: alarm, Position: PinPositionLast}}}
+	result, err := NewSelector(opts).Select(context.Background(), pool, 20*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if result.TotalMs != 20*MillisecondsPerMinute {
+		t.Errorf("Expected total 20min, got %d", result.TotalMs)
+	}
+	if last := result.Tracks[len(result.Tracks)-1]; last.Uri != "alarm" {
+		t.Errorf("Expected pinned track at the end, got %s", last.Uri)
+	}
+	count := 0
+	for _, track := range result.Tracks {
+		if track.Uri == "alarm" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected pinned track exactly once, got %d", count)
+	}
+}
+
+// TestSelector_PinnedTooLong は、固定曲だけで指定時間を超える場合に
+// ErrInvalidPinnedTracks が返ることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 12分の固定曲
+//   - 要求: 5分
+//   - 期待結果: ErrInvalidPinnedTracks、details.reason = "too_long"
+func TestSelector_PinnedTooLong(t *testing.T) {
+	epic := model.Track{Uri: "epic", DurationMs: 720000}
+
+	_, err := NewSelector(Options{Seed: 1, Pins: []Pin{{Track: epic}}}).Select(context.Background(), nil, 5*MillisecondsPerMinute)
+
+	if !errors.Is(err, model.ErrInvalidPinnedTracks) {
+		t.Fatalf("Expected ErrInvalidPinnedTracks, got %v", err)
+	}
+	if details := model.ErrorDetails(err); details["reason"] != "too_long" {
+		t.Errorf("Unexpected error details: %v", details)
+	}
+}
+
+// TestSelector_PinnedWithDiversity は、固定曲もアーティストの曲数の上限に数え、
+// 固定曲との境目でも同じアーティストの曲が隣り合わないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3アーティスト各5曲（すべて1分）
+//   - 固定曲: artist0 の曲（first）、artist0 の曲（位置指定なし）、artist1 の曲（last）
+//   - 要求: 9分、maxTracksPerArtist=3、noConsecutiveSameArtist=true
+//   - 期待結果: 成功、固定曲を含めてどのアーティストも3曲以下、
+//     先頭と末尾が固定曲、固定曲は1回ずつ含まれ、隣り合う曲のアーティストがすべて異なる
+func TestSelector_PinnedWithDiversity(t *testing.T) {
+	pool := artistTracks(3, 5)
+	pins := []Pin{
+		{Track: model.Track{Uri: "pin-first", DurationMs: 60000, ArtistsId: []string{"artist0"}}, Position: PinPositionFirst},
+		{Track: model.Track{Uri: "pin-any", DurationMs: 60000, ArtistsId: []string{"artist0"}}},
+		{Track: model.Track{Uri: "pin-last", DurationMs: 60000, ArtistsId: []string{"artist1"}}, Position: PinPositionLast},
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		opts := Options{Seed: seed, Pins: pins, MaxTracksPerArtist: 3, NoConsecutiveSameArtist: true}
+		result, err := NewSelector(opts).Select(context.Background(), pool, 9*MillisecondsPerMinute)
+		if err != nil {
+			t.Fatalf("seed %d: Select() unexpected error: %v", seed, err)
+		}
+		if len(result.Tracks) != 9 {
+			t.Fatalf("seed %d: expected 9 tracks, got %d", seed, len(result.Tracks))
+		}
+		if result.Tracks[0].Uri != "pin-first" || result.Tracks[8].Uri != "pin-last" {
+			t.Errorf("seed %d: expected pinned tracks at both ends, got %s ... %s", seed, result.Tracks[0].Uri, result.Tracks[8].Uri)
+		}
+
+		counts := make(map[string]int)
+		uris := make(map[string]int)
+		for i, track := range result.Tracks {
+			counts[track.ArtistsId[0]]++
+			uris[track.Uri]++
+			if i > 0 && sharesArtist(result.Tracks[i-1], track) {
+				t.Errorf("seed %d: tracks %d and %d share an artist", seed, i-1, i)
+			}
+		}
+		for artist, c := range counts {
+			if c > 3 {
+				t.Errorf("seed %d: artist %s has %d tracks including pins, expected at most 3", seed, artist, c)
+			}
+		}
+		for _, p := range pins {
+			if uris[p.Track.Uri] != 1 {
+				t.Errorf("seed %d: expected %s exactly once, got %d", seed, p.Track.Uri, uris[p.Track.Uri])
+			}
+		}
+	}
+}
+
+// TestResolvePins_Missing は、プロバイダで見つからなかった固定曲が
+// エラーの追加情報に含まれることをテストする。
+func TestResolvePins_Missing(t *testing.T) {
+	pinned := []PinnedTrack{{Uri: "found"}, {Uri: "missing", Position: PinPositionFirst}}
+	found := []model.Track{{Uri: "found", DurationMs: 180000}}
+
+	_, err := ResolvePins(pinned, found, func(t model.Track) string { return t.Uri })
+
+	if !errors.Is(err, model.ErrInvalidPinnedTracks) {
+		t.Fatalf("Expected ErrInvalidPinnedTracks, got %v", err)
+	}
+	missing, _ := model.ErrorDetails(err)["missing"].([]string)
+	if len(missing) != 1 || missing[0] != "missing" {
+		t.Errorf("Expected missing = [missing], got %v", missing)
+	}
+}
@@ -0,0 +1,54 @@
+package track
+
+// FitMode は指定時間に対する合計再生時間の合わせ方
+type FitMode string
+
+const (
+	// FitModeBalanced は指定時間の前後どちらの誤差も許容する（デフォルト）
+	FitModeBalanced FitMode = ""
+	// FitModeExact は完全一致のみ許容する
+	FitModeExact FitMode = "exact"
+	// FitModeUnderOnly は指定時間を超えない（タイマーより先に曲が終わる）
+	// 例: 瞑想のベルより前に音楽を終わらせたい場合
+	FitModeUnderOnly FitMode = "under-only"
+	// FitModeOverOnly は指定時間より早く終わらない（タイマーまで音楽が途切れない）
+	// 例: ワークアウト中に音楽を止めたくない場合
+	FitModeOverOnly FitMode = "over-only"
+)
+
+// FitPolicy は合計再生時間の許容範囲を決める設定
+type FitPolicy struct {
+	ToleranceMs int     // 許容誤差
+	Mode        FitMode // 誤差を許容する方向
+	CrossfadeMs int     // クロスフェードで曲が重なる時間（0の場合は重ならない）
+}
+
+// DefaultFitPolicy は従来の許容誤差ルールに従った設定を返す。
+// 10分以上のプレイリストでは ±AllowanceMs（15秒）、10分未満では完全一致のみ。
+func DefaultFitPolicy(totalPlayTimeMs int) FitPolicy {
+	if totalPlayTimeMs >= MinPlaylistDurationForAllowanceMs {
+		return FitPolicy{ToleranceMs: AllowanceMs}
+	}
+	return FitPolicy{}
+}
+
+// window は設定に従って合計再生時間の許容範囲を返す
+func (p FitPolicy) window(totalPlayTimeMs int) window {
+	tolerance := p.ToleranceMs
+	if tolerance < 0 || p.Mode == FitModeExact {
+		tolerance = 0
+	}
+
+	w := window{
+		target: totalPlayTimeMs,
+		lo:     totalPlayTimeMs - tolerance,
+		hi:     totalPlayTimeMs + tolerance,
+	}
+	switch p.Mode {
+	case FitModeUnderOnly:
+		w.hi = totalPlayTimeMs
+	case FitModeOverOnly:
+		w.lo = totalPlayTimeMs
+	}
+	return w
+}
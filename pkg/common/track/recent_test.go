@@ -0,0 +1,78 @@
+package track
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// 最近使った曲を避ける選曲のテスト
+// =============================================================================
+// Options.Recent が指定された場合に、Selector が以下を満たすことをテストする:
+// 1. 候補が十分あれば最近使った曲を選ばない
+// 2. 候補が足りない場合は最近使った曲も含めて選曲する（エラーにしない）
+// =============================================================================
+
+// minuteTracks は1分の曲を n 曲生成するテスト用ヘルパー
+func minuteTracks(prefix string, n int) []model.Track {
+	tracks := make([]model.Track, n)
+	for i := range tracks {
+		tracks[i] = model.Track{Uri: fmt.Sprintf("%s%d", prefix, i), DurationMs: 60000}
+	}
+	return tracks
+}
+
+// TestSelector_AvoidRecent は、候補が十分ある場合に最近使った曲が選ばれないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 最近使った1分の曲5曲 + 新しい1分の曲5曲
+//   - 要求: 5分
+//   - 期待結果: 成功、新しい曲のみ、recent_excluded = 5
+func TestSelector_AvoidRecent(t *testing.T) {
+	recent := minuteTracks("recent", 5)
+	pool := append(minuteTracks("fresh", 5), recent...)
+
+	result, err := NewSelector(Options{Seed: 1, Recent: [][]model.Track{recent}}).Select(context.Background(), pool, 5*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	for _, track := range result.Tracks {
+		if track.Uri[:6] == "recent" {
+			t.Errorf("Recently used track %s was selected", track.Uri)
+		}
+	}
+	if result.Diagnostics.RecentExcluded != 5 {
+		t.Errorf("Expected 5 recent excluded, got %d", result.Diagnostics.RecentExcluded)
+	}
+}
+
+// TestSelector_AvoidRecent_Fallback は、候補が足りない場合に
+// 古いプレイリストの曲から順に使うことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 新しい曲2曲、直近のプレイリストの曲4曲、1つ前のプレイリストの曲4曲（すべて1分）
+//   - 要求: 6分
+//   - 期待結果: 成功、直近のプレイリストの曲は選ばれない（1つ前の曲で補う）
+func TestSelector_AvoidRecent_Fallback(t *testing.T) {
+	newest := minuteTracks("newest", 4)
+	older := minuteTracks("older", 4)
+	pool := append(append(minuteTracks("fresh", 2), newest...), older...)
+
+	result, err := NewSelector(Options{Seed: 1, Recent: [][]model.Track{newest, older}}).Select(context.Background(), pool, 6*MillisecondsPerMinute)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	for _, track := range result.Tracks {
+		if track.Uri[:6] == "newest" {
+			t.Errorf("Track %s from the newest playlist was selected", track.Uri)
+		}
+	}
+	if result.Diagnostics.RecentExcluded != 4 {
+		t.Errorf("Expected 4 recent excluded, got %d", result.Diagnostics.RecentExcluded)
+	}
+}
@@ -0,0 +1,214 @@
+package track
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// 区間の選曲元
+const (
+	SegmentSourceCatalog   = "catalog"   // グローバルカタログ（Spotifyのみ）
+	SegmentSourceFavorites = "favorites" // お気に入りの曲
+	SegmentSourceArtists   = "artists"   // 指定アーティストの曲
+)
+
+// インターバルプレイリストの区間数の上限（繰り返し展開後）
+const MaxSegments = 48
+
+// Segment はインターバルプレイリスト（ポモドーロ、HIITなど）の1区間の指定
+type Segment struct {
+	Duration
+	Label     string   `json:"label" binding:"required,max=32"`
+	Source    string   `json:"source" binding:"required,oneof=catalog favorites artists"`
+	ArtistIds []string `json:"artistIds"`
+}
+
+// poolKey は同じ候補プールを使う区間を見分けるためのキー
+func (s Segment) poolKey() string {
+	if s.Source != SegmentSourceArtists {
+		return s.Source
+	}
+	return s.Source + ":" + strings.Join(s.ArtistIds, ",")
+}
+
+// IntervalParams はインターバルプレイリスト作成リクエストの区間指定。
+// 例: [{work:25m},{break:5m}] x4 は Segments に2区間、Repeat に4を指定する。
+type IntervalParams struct {
+	Segments []Segment `json:"segments" binding:"required,min=1,dive"`
+	Repeat   int       `json:"repeat" binding:"omitempty,min=1"`
+}
+
+// Expand は繰り返しを展開した区間の一覧と、各区間の再生時間（ms）を返す。
+// 区間数や合計時間が上限を超える場合、artists に artistIds がない場合は
+// model.ErrInvalidSegment を返す。
+func (p IntervalParams) Expand() ([]Segment, []int, error) {
+	repeat := p.Repeat
+	if repeat == 0 {
+		repeat = 1
+	}
+	if len(p.Segments)*repeat > MaxSegments {
+		return nil, nil, model.ErrInvalidSegment
+	}
+
+	durations := make([]int, len(p.Segments))
+	totalMs := 0
+	for i, seg := range p.Segments {
+		ms, err := seg.TotalMs()
+		if err != nil {
+			return nil, nil, err
+		}
+		if seg.Source == SegmentSourceArtists && len(seg.ArtistIds) == 0 {
+			return nil, nil, model.ErrInvalidSegment
+		}
+		durations[i] = ms
+		totalMs += ms
+	}
+	if totalMs*repeat > MaxPlaylistDurationMs {
+		return nil, nil, model.ErrInvalidDuration
+	}
+
+	segments := make([]Segment, 0, len(p.Segments)*repeat)
+	segmentMs := make([]int, 0, len(p.Segments)*repeat)
+	for r := 0; r < repeat; r++ {
+		segments = append(segments, p.Segments...)
+		segmentMs = append(segmentMs, durations...)
+	}
+	return segments, segmentMs, nil
+}
+
+// PoolFunc は区間の選曲元に対応する候補プールを返す
+type PoolFunc func(seg Segment) ([]model.Track, error)
+
+// FillSegments は各区間を独立に選曲し、連結した曲と区間の境界を返す。
+// 選曲元が同じ区間では候補プールを使い回し、先の区間で使った曲は後の区間で選ばない。
+// 各区間は opts.Seed から導いたシードで選曲するため、同じシードなら同じ結果になる。
+// CrossfadeMs が指定されていれば、区間の最初の曲が前の区間の最後の曲と重なる分も考慮する。
+// Debug が指定されていれば、区間の境界に区間ごとの診断情報を付ける。
+func FillSegments(ctx context.Context, segments []Segment, segmentMs []int, opts Options, poolFunc PoolFunc) ([]model.Track, []model.SegmentBoundary, error) {
+	pools := make(map[string][]model.Track)
+	used := make(map[string]bool)
+	seeds := rand.New(rand.NewSource(opts.Seed))
+
+	var tracks []model.Track
+	boundaries := make([]model.SegmentBoundary, 0, len(segments))
+	offsetMs := 0
+
+	for i, seg := range segments {
+		key := seg.poolKey()
+		pool, ok := pools[key]
+		if !ok {
+			var err error
+			pool, err = poolFunc(seg)
+			if err != nil {
+				return nil, nil, err
+			}
+			pools[key] = pool
+		}
+
+		candidates := make([]model.Track, 0, len(pool))
+		for _, t := range pool {
+			if !used[trackKey(t)] {
+				candidates = append(candidates, t)
+			}
+		}
+
+		segOpts := opts
+		segOpts.Seed = seeds.Int63n(MaxSeed)
+		segOpts.Source = opts.Source + "/" + seg.Source
+		segOpts.Origin = seg.Source
+		if seg.Source == SegmentSourceCatalog {
+			segOpts.GapFill = nil
+		}
+		// 2つ目以降の区間は最初の曲が前の区間と重なるため、重なりの分だけ長く選ぶ
+		overlapMs := 0
+		if len(tracks) > 0 {
+			overlapMs = opts.CrossfadeMs
+		}
+		result, err := NewSelector(segOpts).Select(ctx, candidates, segmentMs[i]+overlapMs)
+		if err != nil {
+			return nil, nil, err
+		}
+		segmentTotalMs := result.TotalMs - overlapMs
+
+		for _, t := range result.Tracks {
+			used[trackKey(t)] = true
+		}
+		tracks = append(tracks, result.Tracks...)
+		boundary := model.SegmentBoundary{
+			Label:       seg.Label,
+			Source:      seg.Source,
+			StartMs:     offsetMs,
+			EndMs:       offsetMs + segmentTotalMs,
+			TrackCount:  len(result.Tracks),
+			DeltaMs:     segmentTotalMs - segmentMs[i],
+			Approximate: result.Approximation != nil,
+		}
+		if opts.Debug {
+			diag := result.Diagnostics
+			diag.RemainderMs = -boundary.DeltaMs
+			boundary.Diagnostics = &diag
+		}
+		boundaries = append(boundaries, boundary)
+		offsetMs += segmentTotalMs
+	}
+
+	return tracks, boundaries, nil
+}
+
+// SegmentsDeltaMs は区間ごとの指定時間との差の合計を返す
+func SegmentsDeltaMs(boundaries []model.SegmentBoundary) int {
+	delta := 0
+	for _, b := range boundaries {
+		delta += b.DeltaMs
+	}
+	return delta
+}
+
+// SegmentsDetails は近似で選曲した区間がある場合に、レスポンスの details に出す説明を返す。
+// すべての区間が許容範囲内で選べた場合は nil を返す。
+func SegmentsDetails(boundaries []model.SegmentBoundary) map[string]interface{} {
+	var approximate []int
+	for i, b := range boundaries {
+		if b.Approximate {
+			approximate = append(approximate, i)
+		}
+	}
+	if len(approximate) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"reason":   "approximate",
+		"segments": approximate,
+		"delta_ms": SegmentsDeltaMs(boundaries),
+	}
+}
+
+// SegmentsDiagnostics は区間ごとの診断情報を足し合わせた、プレイリスト全体の診断情報を返す。
+// 区間に診断情報がない（Debug が指定されていない）場合は nil を返す。
+func SegmentsDiagnostics(boundaries []model.SegmentBoundary) *model.SelectionDiagnostics {
+	var diag *Diagnostics
+	for _, b := range boundaries {
+		if b.Diagnostics == nil {
+			continue
+		}
+		if diag == nil {
+			diag = &Diagnostics{}
+		}
+		addDiagnostics(diag, *b.Diagnostics)
+	}
+	if diag != nil {
+		diag.RemainderMs = -SegmentsDeltaMs(boundaries)
+	}
+	return diag
+}
+
+// trackKey は曲を一意に識別するキー（Spotify は URI、SoundCloud は ID）
+func trackKey(t model.Track) string {
+	if t.Uri != "" {
+		return t.Uri
+	}
+	return t.ID
+}
@@ -0,0 +1,209 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// IntervalParams.Expand 関数のテスト
+// =============================================================================
+// Expand は区間指定を繰り返し回数分だけ展開する関数。
+// =============================================================================
+
+// TestIntervalParams_Expand は、ポモドーロ形式の指定が展開されることをテストする。
+//
+// テストシナリオ:
+//   - 入力: [{work:25分},{break:5分}] x4
+//   - 期待結果: 8区間、work と break が交互に並ぶ
+func TestIntervalParams_Expand(t *testing.T) {
+	params := IntervalParams{
+		Segments: []Segment{
+			{Label: "work", Source: SegmentSourceFavorites, Duration: Duration{Minute: 25}},
+			{Label: "break", Source: SegmentSourceCatalog, Duration: Duration{Minute: 5}},
+		},
+		Repeat: 4,
+	}
+
+	segments, segmentMs, err := params.Expand()
+
+	if err != nil {
+		t.Fatalf("Expand() unexpected error: %v", err)
+	}
+	if len(segments) != 8 || len(segmentMs) != 8 {
+		t.Fatalf("Expected 8 segments, got %d", len(segments))
+	}
+	for i, seg := range segments {
+		expectedLabel, expectedMs := "work", 25*MillisecondsPerMinute
+		if i%2 == 1 {
+			expectedLabel, expectedMs = "break", 5*MillisecondsPerMinute
+		}
+		if seg.Label != expectedLabel || segmentMs[i] != expectedMs {
+			t.Errorf("segment %d = %s/%d, expected %s/%d", i, seg.Label, segmentMs[i], expectedLabel, expectedMs)
+		}
+	}
+}
+
+// TestIntervalParams_Expand_Invalid は、不正な区間指定がエラーになることをテストする。
+//
+// テストケース:
+//   - artists なのに artistIds がない: ErrInvalidSegment
+//   - 区間数が上限を超える: ErrInvalidSegment
+//   - 合計時間が24時間を超える: ErrInvalidDuration
+func TestIntervalParams_Expand_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      IntervalParams
+		expectedErr error
+	}{
+		{
+			name: "artists without artistIds",
+			params: IntervalParams{
+				Segments: []Segment{{Label: "work", Source: SegmentSourceArtists, Duration: Duration{Minute: 25}}},
+			},
+			expectedErr: model.ErrInvalidSegment,
+		},
+		{
+			name: "too many segments",
+			params: IntervalParams{
+				Segments: []Segment{{Label: "work", Source: SegmentSourceCatalog, Duration: Duration{Minute: 1}}},
+				Repeat:   MaxSegments + 1,
+			},
+			expectedErr: model.ErrInvalidSegment,
+		},
+		{
+			name: "total too long",
+			params: IntervalParams{
+				Segments: []Segment{{Label: "work", Source: SegmentSourceCatalog, Duration: Duration{Minute: 60}}},
+				Repeat:   25,
+			},
+			expectedErr: model.ErrInvalidDuration,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.params.Expand()
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expand() error = %v, expected %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// FillSegments 関数のテスト
+// =============================================================================
+// FillSegments は区間ごとに独立して選曲し、結果を連結する関数。
+// 以下のロジックをテストする:
+// 1. 各区間が指定時間ちょうどに埋まり、境界のオフセットが連続する
+// 2. 同じ選曲元を使う区間の間で曲が重複しない
+// 3. 選曲元ごとに候補プールを1回だけ取得する
+// =============================================================================
+
+// TestFillSegments は、work/break の繰り返しが重複なく埋まることをテストする。
+//
+// テストシナリオ:
+//   - favorites: 1分の曲 100曲、catalog: 30秒の曲 100曲
+//   - 要求: [{work:5分},{break:1分}] x3
+//   - 期待結果: 6区間、境界が連続、全体で曲の重複なし、プール取得は2回
+func TestFillSegments(t *testing.T) {
+	pools := map[string][]model.Track{}
+	for i := 0; i < 100; i++ {
+		pools[SegmentSourceFavorites] = append(pools[SegmentSourceFavorites], model.Track{Uri: fmt.Sprintf("fav%d", i), DurationMs: 60000})
+		pools[SegmentSourceCatalog] = append(pools[SegmentSourceCatalog], model.Track{Uri: fmt.Sprintf("cat%d", i), DurationMs: 30000})
+	}
+	fetches := 0
+	poolFunc := func(seg Segment) ([]model.Track, error) {
+		fetches++
+		return pools[seg.Source], nil
+	}
+
+	segments, segmentMs, err := IntervalParams{
+		Segments: []Segment{
+			{Label: "work", Source: SegmentSourceFavorites, Duration: Duration{Minute: 5}},
+			{Label: "break", Source: SegmentSourceCatalog, Duration: Duration{Minute: 1}},
+		},
+		Repeat: 3,
+	}.Expand()
+	if err != nil {
+		t.Fatalf("Expand() unexpected error: %v", err)
+	}
+
+	tracks, boundaries, err := FillSegments(context.Background(), segments, segmentMs, Options{Seed: 1}, poolFunc)
+
+	if err != nil {
+		t.Fatalf("FillSegments() unexpected error: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("Expected pool to be fetched once per source, got %d fetches", fetches)
+	}
+	if len(boundaries) != 6 {
+		t.Fatalf("Expected 6 boundaries, got %d", len(boundaries))
+	}
+	offset := 0
+	for i, b := range boundaries {
+		if b.StartMs != offset || b.EndMs-b.StartMs != segmentMs[i] {
+			t.Errorf("boundary %d = [%d, %d], expected start %d and length %d", i, b.StartMs, b.EndMs, offset, segmentMs[i])
+		}
+		offset = b.EndMs
+	}
+	if total := sumDuration(tracks); total != offset {
+		t.Errorf("Expected total duration %d, got %d", offset, total)
+	}
+
+	seen := make(map[string]bool)
+	for _, track := range tracks {
+		if seen[track.Uri] {
+			t.Fatalf("Duplicate track across segments: %s", track.Uri)
+		}
+		seen[track.Uri] = true
+	}
+}
+
+// TestFillSegments_Diagnostics は、Debug 指定時に区間ごとの診断情報を付けることをテストする。
+//
+// テストシナリオ:
+//   - favorites: 1分の曲 100曲
+//   - 要求: [{work:5分},{break:3分}]
+//   - Debug あり → 各区間に診断情報、全体は区間ごとの合計
+//   - Debug なし → 診断情報なし
+func TestFillSegments_Diagnostics(t *testing.T) {
+	poolFunc := func(seg Segment) ([]model.Track, error) {
+		return minuteTracks("fav", 100), nil
+	}
+	segments := []Segment{{Label: "work", Source: SegmentSourceFavorites}, {Label: "break", Source: SegmentSourceFavorites}}
+	segmentMs := []int{5 * MillisecondsPerMinute, 3 * MillisecondsPerMinute}
+
+	_, boundaries, err := FillSegments(context.Background(), segments, segmentMs, Options{Seed: 1, Debug: true}, poolFunc)
+	if err != nil {
+		t.Fatalf("FillSegments() unexpected error: %v", err)
+	}
+	poolSize := 0
+	for i, b := range boundaries {
+		if b.Diagnostics == nil {
+			t.Fatalf("boundary %d: expected diagnostics", i)
+		}
+		poolSize += b.Diagnostics.PoolSize
+	}
+	// 2つ目の区間は1つ目で使った5曲を除いた95曲から選ぶ
+	if poolSize != 195 {
+		t.Errorf("Expected pool sizes to sum to 195, got %d", poolSize)
+	}
+	diag := SegmentsDiagnostics(boundaries)
+	if diag == nil || diag.PoolSize != poolSize || diag.RemainderMs != 0 {
+		t.Errorf("Expected combined diagnostics with pool %d and remainder 0, got %+v", poolSize, diag)
+	}
+
+	_, boundaries, err = FillSegments(context.Background(), segments, segmentMs, Options{Seed: 1}, poolFunc)
+	if err != nil {
+		t.Fatalf("FillSegments() unexpected error: %v", err)
+	}
+	if diag := SegmentsDiagnostics(boundaries); diag != nil {
+		t.Errorf("Expected no diagnostics without debug, got %+v", diag)
+	}
+}
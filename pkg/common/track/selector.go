@@ -1,12 +1,401 @@
 package track
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
 	"github.com/pp-develop/music-timer-api/model"
 )
 
+// Options は Selector の動作を指定する
+type Options struct {
+	// Source はログに出力する選曲元（例: "spotify/favorites"）
+	Source string
+	// Seed は選曲に使う乱数シード（同じプールと同じシードなら同じ結果になる）
+	Seed int64
+	// ToleranceMs は許容誤差（nil の場合は DefaultFitPolicy に従う）
+	ToleranceMs *int
+	// Mode は誤差を許容する方向
+	Mode FitMode
+	// MaxTracksPerArtist は1アーティストあたりの最大曲数（0の場合は制限なし）
+	MaxTracksPerArtist int
+	// NoConsecutiveSameArtist が true の場合、同じアーティストの曲が連続しないように並べる
+	NoConsecutiveSameArtist bool
+	// MinTrackMs / MaxTrackMs は候補にする曲の再生時間の範囲（0の場合は制限なし）
+	MinTrackMs int
+	MaxTrackMs int
+	// Pins は必ず含める曲。合計再生時間を指定時間から差し引いて残りを選曲する。
+	Pins []Pin
+	// Blocklist はユーザーが除外した曲とアーティスト（固定曲には適用しない）
+	Blocklist *Blocklist
+	// Recent は最近生成したプレイリストの曲（新しいプレイリストから順）。できるだけ選ばないようにする。
+	Recent [][]model.Track
+	// AllowApproximate が true の場合、許容範囲内の組み合わせがなければ指定時間に最も近い組み合わせを返す
+	AllowApproximate bool
+	// GapFill が指定された場合、候補プールだけで指定時間に合わなければ最後の隙間を GapFill の曲で埋める
+	GapFill GapFillFunc
+	// Origin は GapFill 指定時に候補プールの曲に付ける出自（例: "favorites"）
+	Origin string
+	// CrossfadeMs はクライアントのクロスフェードで曲が重なる時間。
+	// 0より大きい場合は重なりを引いた実際の再生時間を指定時間に合わせる。
+	CrossfadeMs int
+	// Debug が true の場合、選曲エラーの details に診断情報を含める
+	Debug bool
+	// Timeout は組み合わせ探索の制限時間（0の場合は DefaultTimeoutSeconds）
+	Timeout time.Duration
+	// LogAttrs は選曲結果のログに付与する追加情報（マーケット、アーティスト数など）
+	LogAttrs []slog.Attr
+
+	// reserved は選曲結果とは別にプレイリストに含める曲（FillMix で先に選んだ選曲元の曲など）。
+	// 固定曲とあわせて1アーティストあたりの曲数に数える。
+	reserved []model.Track
+}
+
+// Policy は指定時間に対する許容範囲の設定を返す
+func (o Options) Policy(totalPlayTimeMs int) FitPolicy {
+	policy := DefaultFitPolicy(totalPlayTimeMs)
+	if o.ToleranceMs != nil {
+		policy.ToleranceMs = *o.ToleranceMs
+	}
+	policy.Mode = o.Mode
+	policy.CrossfadeMs = o.CrossfadeMs
+	return policy
+}
+
+// Diagnostics は選曲処理の診断情報
+type Diagnostics = model.SelectionDiagnostics
+
+// Result は Selector による選曲結果
+type Result struct {
+	Tracks      []model.Track
+	TotalMs     int
+	Diagnostics Diagnostics
+	// Approximation は近似で代用した場合の説明（許容範囲内で選べた場合は nil）
+	Approximation *Approximation
+}
+
+// Selector は候補プールから指定時間に合う曲を選ぶ、全プロバイダ共通の選曲エンジン。
+// Spotify / SoundCloud のどの選曲元でも、再生時間不足（ErrNotEnoughTracks）と
+// 組み合わせなし（ErrTimeoutCreatePlaylist）の判定とログ出力を同じ基準で行う。
+type Selector struct {
+	opts Options
+	rng  *rand.Rand
+}
+
+// NewSelector は Selector を生成する
+func NewSelector(opts Options) *Selector {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Duration(DefaultTimeoutSeconds) * time.Second
+	}
+	return &Selector{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)),
+	}
+}
+
+// Select は候補プールから合計再生時間が targetMs に合う曲を選ぶ。
+// CrossfadeMs が指定されていれば、曲の重なりを引いた実際の再生時間で合わせる。
+func (s *Selector) Select(ctx context.Context, pool []model.Track, targetMs int) (*Result, error) {
+	if s.opts.CrossfadeMs > 0 {
+		return s.selectCrossfaded(ctx, pool, targetMs)
+	}
+	start := time.Now()
+
+	// 近似の探索は厳密な選曲とは別に制限時間を設けるため、元のコンテキストを残しておく
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	pool, pinnedMs := excludePinned(pool, s.opts.Pins)
+	pool, blocked := excludeBlocked(pool, s.opts.Blocklist)
+	pool, filteredOut := filterByTrackLength(pool, s.opts.MinTrackMs, s.opts.MaxTrackMs)
+	diag := Diagnostics{PoolSize: len(pool), FilteredOut: filteredOut, Blocked: blocked}
+	for _, t := range pool {
+		diag.PoolDurationMs += t.DurationMs
+	}
+	diag.DurationHistogram = durationHistogram(pool)
+
+	policy := s.opts.Policy(targetMs)
+	w := policy.window(targetMs).shift(pinnedMs)
+	if w.hi < 0 {
+		return nil, model.WithDetails(model.ErrInvalidPinnedTracks, map[string]interface{}{
+			"reason":      "too_long",
+			"pinned_ms":   pinnedMs,
+			"required_ms": targetMs,
+		})
+	}
+	tracks, attempts, recentExcluded, err := s.solveAvoidingRecent(ctx, pool, w, true)
+	cause := err
+	if err != nil && s.opts.GapFill != nil {
+		var a int
+		tracks, a, diag.GapFilled, err = s.fillGap(parent, pool, w, err)
+		attempts += a
+		recentExcluded = 0
+	}
+	if err != nil && s.opts.AllowApproximate {
+		var a int
+		tracks, a, recentExcluded, err = s.approximate(parent, pool, w, err)
+		attempts += a
+	}
+	diag.Attempts = attempts
+	diag.RecentExcluded = recentExcluded
+	diag.TopFilter = topFilter(diag)
+	diag.ElapsedMs = time.Since(start).Milliseconds()
+	if errors.Is(err, model.ErrNotEnoughTracks) && targetMs > diag.PoolDurationMs+pinnedMs {
+		diag.RemainderMs = targetMs - diag.PoolDurationMs - pinnedMs
+	}
+
+	attrs := []any{
+		slog.String("source", s.opts.Source),
+		slog.Int64("seed", s.opts.Seed),
+		slog.Int("required_ms", targetMs),
+		slog.Int("tolerance_ms", policy.ToleranceMs),
+		slog.String("mode", string(policy.Mode)),
+		slog.Int("max_tracks_per_artist", s.opts.MaxTracksPerArtist),
+		slog.Bool("no_consecutive_same_artist", s.opts.NoConsecutiveSameArtist),
+		slog.Int("min_track_ms", s.opts.MinTrackMs),
+		slog.Int("max_track_ms", s.opts.MaxTrackMs),
+		slog.Bool("allow_approximate", s.opts.AllowApproximate),
+		slog.Bool("gap_fill", s.opts.GapFill != nil),
+		slog.Int("gap_filled", diag.GapFilled),
+		slog.Int("filtered_out", diag.FilteredOut),
+		slog.Int("blocked", diag.Blocked),
+		slog.Int("recent_playlists", len(s.opts.Recent)),
+		slog.Int("recent_excluded", diag.RecentExcluded),
+		slog.String("top_filter", diag.TopFilter),
+		slog.Int("pinned_count", len(s.opts.Pins)),
+		slog.Int("pinned_ms", pinnedMs),
+		slog.Int("available_ms", diag.PoolDurationMs),
+		slog.Int("track_count", diag.PoolSize),
+		slog.Int("attempts", diag.Attempts),
+		slog.Int64("elapsed_ms", diag.ElapsedMs),
+	}
+	for _, a := range s.opts.LogAttrs {
+		attrs = append(attrs, a)
+	}
+
+	if err != nil {
+		if errors.Is(err, model.ErrNotEnoughTracks) {
+			slog.Warn("not enough tracks", attrs...)
+			if diag.FilteredOut > 0 {
+				err = model.WithDetails(err, map[string]interface{}{
+					"reason":       "track_length_filter",
+					"min_track_ms": s.opts.MinTrackMs,
+					"max_track_ms": s.opts.MaxTrackMs,
+					"filtered_out": diag.FilteredOut,
+					"available_ms": diag.PoolDurationMs,
+					"required_ms":  targetMs,
+				})
+			} else {
+				err = model.WithDetails(err, map[string]interface{}{
+					"reason":       "insufficient_duration",
+					"available_ms": diag.PoolDurationMs + pinnedMs,
+					"required_ms":  targetMs,
+				})
+			}
+		} else {
+			slog.Warn("combination not found", attrs...)
+			if errors.Is(err, model.ErrTimeoutCreatePlaylist) {
+				err = model.WithDetails(err, map[string]interface{}{
+					"reason":       "no_match",
+					"required_ms":  targetMs,
+					"tolerance_ms": policy.ToleranceMs,
+					"mode":         string(policy.Mode),
+				})
+			}
+		}
+		if s.opts.Debug {
+			err = withDiagnostics(err, diag)
+		}
+		return nil, err
+	}
+
+	if s.opts.GapFill != nil {
+		markOrigins(tracks, s.opts.Pins, s.opts.Origin)
+	}
+	result := &Result{
+		Tracks:      tracks,
+		Diagnostics: diag,
+	}
+	for _, t := range tracks {
+		result.TotalMs += t.DurationMs
+	}
+	result.Diagnostics.RemainderMs = targetMs - result.TotalMs
+
+	// 近似の探索で選んだ結果が許容範囲外なら、その旨を結果に残す
+	if full := policy.window(targetMs); cause != nil && (result.TotalMs < full.lo || result.TotalMs > full.hi) {
+		result.Approximation = &Approximation{
+			Cause:       approximationCause(cause),
+			RequiredMs:  targetMs,
+			TotalMs:     result.TotalMs,
+			ToleranceMs: policy.ToleranceMs,
+		}
+		attrs = append(attrs, slog.Int("delta_ms", result.Approximation.DeltaMs()))
+		slog.Warn("approximate selection used", attrs...)
+	}
+
+	slog.Info("track selection completed", append(attrs, slog.Int("selected_count", len(tracks)), slog.Int("remainder_ms", result.Diagnostics.RemainderMs))...)
+	return result, nil
+}
+
+// solveAvoidingRecent は最近のプレイリストで使った曲を除いて組み合わせを探す。
+// 候補が足りず見つからない場合は、避ける対象を新しい半分のプレイリストに絞って探し直し、
+// 最終的には最近使った曲も含めた候補プール全体で探す。
+// 避けた曲数もあわせて返す。arrange は solve と同じ。
+func (s *Selector) solveAvoidingRecent(ctx context.Context, pool []model.Track, w window, arrange bool) ([]model.Track, int, int, error) {
+	attempts := 0
+	for n := len(s.opts.Recent); n > 0; n /= 2 {
+		candidates, excluded := excludeRecent(pool, s.opts.Recent[:n])
+		if excluded == 0 {
+			break
+		}
+
+		tracks, a, err := s.solve(ctx, candidates, w, arrange)
+		attempts += a
+		if err == nil {
+			return tracks, attempts, excluded, nil
+		}
+		if ctx.Err() != nil {
+			return nil, attempts, 0, err
+		}
+		slog.Debug("falling back to include recent tracks",
+			slog.String("source", s.opts.Source),
+			slog.Int("recent_playlists", n),
+			slog.Int("recent_excluded", excluded),
+			slog.Any("error", err))
+	}
+
+	tracks, a, err := s.solve(ctx, pool, w, arrange)
+	return tracks, attempts + a, 0, err
+}
+
+// excludeRecent は候補プールから最近のプレイリストで使った曲を除き、除いた曲数とともに返す
+func excludeRecent(pool []model.Track, recent [][]model.Track) ([]model.Track, int) {
+	used := make(map[string]bool)
+	for _, tracks := range recent {
+		for _, t := range tracks {
+			used[trackKey(t)] = true
+		}
+	}
+	if len(used) == 0 {
+		return pool, 0
+	}
+
+	filtered := make([]model.Track, 0, len(pool))
+	for _, t := range pool {
+		if !used[trackKey(t)] {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, len(pool) - len(filtered)
+}
+
+// solve はアーティストの多様性の制約を満たす組み合わせを探す。
+// arrange が true の場合は固定曲を配置して並べたプレイリスト全体を返し、
+// false の場合は選んだ曲だけを返す（呼び出し側で他の曲と合わせて arrange する）。
+// 制約がない場合は部分和探索をそのまま1回行う。
+// 制約がある場合は、アーティストごとの曲数（固定曲も数える）を制限したプールの作り直しと
+// 並べ替えを、制約を満たすまで maxDiversityAttempts 回まで繰り返す。
+func (s *Selector) solve(ctx context.Context, pool []model.Track, w window, arrange bool) ([]model.Track, int, error) {
+	if s.opts.MaxTracksPerArtist <= 0 && !s.opts.NoConsecutiveSameArtist {
+		tracks, n, err := solve(ctx, pool, w, s.rng)
+		if err == nil && arrange {
+			tracks = placePins(tracks, s.opts.Pins, s.rng)
+		}
+		return tracks, n, err
+	}
+
+	attempts := 0
+	var lastErr error
+	for i := 0; i < maxDiversityAttempts; i++ {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+
+		candidates := pool
+		if s.opts.MaxTracksPerArtist > 0 {
+			candidates = capPerArtist(pool, s.opts.MaxTracksPerArtist, s.reservedTracks(), s.rng)
+		}
+
+		tracks, n, err := solve(ctx, candidates, w, s.rng)
+		attempts += n
+		if err != nil {
+			lastErr = err
+			// 曲数の制限がなければプールは毎回同じなので、再試行しても結果は変わらない
+			if s.opts.MaxTracksPerArtist <= 0 {
+				break
+			}
+			continue
+		}
+
+		if arrange {
+			arranged, ok := s.arrange(tracks)
+			if !ok {
+				lastErr = model.ErrTimeoutCreatePlaylist
+				continue
+			}
+			tracks = arranged
+		}
+		return tracks, attempts, nil
+	}
+
+	return nil, attempts, lastErr
+}
+
+// arrange は選んだ曲に固定曲を配置して並べる。
+// NoConsecutiveSameArtist の場合は位置指定のない固定曲も含めて、同じアーティストの曲が隣り合わないように並べる。
+func (s *Selector) arrange(tracks []model.Track) ([]model.Track, bool) {
+	return arrangePins(tracks, s.opts.Pins, s.opts.NoConsecutiveSameArtist, s.rng)
+}
+
+// reservedTracks は1アーティストあたりの曲数に数える、候補プールの外で含める曲を返す
+func (s *Selector) reservedTracks() []model.Track {
+	return append(pinTracks(s.opts.Pins), s.opts.reserved...)
+}
+
+// filterByTrackLength は再生時間が [minMs, maxMs] の範囲外の曲を除外し、除外した曲数とともに返す。
+// minMs, maxMs が0の場合はその側を制限しない。
+func filterByTrackLength(pool []model.Track, minMs, maxMs int) ([]model.Track, int) {
+	if minMs <= 0 && maxMs <= 0 {
+		return pool, 0
+	}
+
+	filtered := make([]model.Track, 0, len(pool))
+	for _, t := range pool {
+		if minMs > 0 && t.DurationMs < minMs {
+			continue
+		}
+		if maxMs > 0 && t.DurationMs > maxMs {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return filtered, len(pool) - len(filtered)
+}
+
 // MakeTracks は指定された総再生時間に合うようにトラックを選択する。
 // 成功したかどうかと、選択されたトラックを返す。
 func MakeTracks(allTracks []model.Track, totalPlayTimeMs int) (bool, []model.Track) {
+	return MakeTracksWithPolicy(allTracks, totalPlayTimeMs, DefaultFitPolicy(totalPlayTimeMs))
+}
+
+// MakeTracksWithPolicy は許容誤差とモードを指定して MakeTracks と同じ選択を行う。
+// policy.CrossfadeMs が指定されていれば、曲の重なりを引いた実際の再生時間で合わせる。
+func MakeTracksWithPolicy(allTracks []model.Track, totalPlayTimeMs int, policy FitPolicy) (bool, []model.Track) {
+	if policy.CrossfadeMs > 0 {
+		c := newCrossfade(policy.CrossfadeMs)
+		policy.CrossfadeMs = 0
+		ok, tracks := MakeTracksWithPolicy(c.shorten(allTracks), totalPlayTimeMs-c.ms, policy)
+		result := &Result{Tracks: tracks}
+		c.restore(result)
+		return ok, result.Tracks
+	}
+
+	w := policy.window(totalPlayTimeMs)
+
 	var tracks []model.Track
 	var totalDuration int
 
@@ -20,7 +409,7 @@ func MakeTracks(allTracks []model.Track, totalPlayTimeMs int) (bool, []model.Tra
 	}
 
 	// オーバーフローを引き起こした最後のトラックを削除
-	if len(tracks) > 0 {
+	if len(tracks) > 0 && totalDuration > totalPlayTimeMs {
 		tracks = tracks[:len(tracks)-1]
 	}
 
@@ -33,22 +422,21 @@ func MakeTracks(allTracks []model.Track, totalPlayTimeMs int) (bool, []model.Tra
 	remainingTime = totalPlayTimeMs - totalDuration
 
 	// 残り時間が0なら完璧にマッチしているので成功
-	if remainingTime == 0 {
+	if remainingTime == 0 && len(tracks) > 0 {
 		return true, tracks
 	}
 
-	// 残り時間が許容誤差（15秒）内かつプレイリストが十分長い（10分以上）場合、
-	// ギャップを埋める必要なし。短いプレイリストでは誤差の影響が大きいため許容しない。
+	// 合計時間が許容範囲の下限以上なら、ギャップを埋める必要なし。
+	// デフォルトでは10分以上のプレイリストのみ15秒の誤差を許容する。
 	// 例: 30分のプレイリストで残り10秒 → 成功（追加曲不要）
 	// 例: 5分のプレイリストで残り10秒 → 追加曲を探す
-	if remainingTime <= AllowanceMs && totalPlayTimeMs >= MinPlaylistDurationForAllowanceMs {
+	if totalDuration >= w.lo && len(tracks) > 0 {
 		return true, tracks
 	}
 
-	// ギャップを埋めるトラックを探す
-	// 10分以上のプレイリストでは許容誤差あり、10分未満では完全一致のみ
+	// ギャップを埋めるトラックを未選択の曲から探す
 	var isTrackFound bool
-	getTrack := GetTrackByDuration(allTracks, remainingTime, totalPlayTimeMs)
+	getTrack := GetTrackByDurationWithPolicy(allTracks[len(tracks):], remainingTime, totalPlayTimeMs, policy)
 	if len(getTrack) > 0 {
 		isTrackFound = true
 		tracks = append(tracks, getTrack...)
@@ -61,21 +449,31 @@ func MakeTracks(allTracks []model.Track, totalPlayTimeMs int) (bool, []model.Tra
 // totalPlayTimeMs が10分以上の場合: durationMs ± AllowanceMs（15秒）の範囲で探索
 // totalPlayTimeMs が10分未満の場合: 完全一致のみ（許容誤差なし）
 func GetTrackByDuration(allTracks []model.Track, durationMs int, totalPlayTimeMs int) []model.Track {
-	// 10分以上なら許容誤差あり、10分未満なら完全一致のみ
-	allowance := 0
-	if totalPlayTimeMs >= MinPlaylistDurationForAllowanceMs {
-		allowance = AllowanceMs
-	}
+	return GetTrackByDurationWithPolicy(allTracks, durationMs, totalPlayTimeMs, DefaultFitPolicy(totalPlayTimeMs))
+}
+
+// GetTrackByDurationWithPolicy は許容誤差とモードを指定して、
+// 残り時間 durationMs を埋めるのに最も近い曲を探す。
+// under-only では残り時間より長い曲、over-only では短い曲を選ばない。
+// policy.CrossfadeMs が指定されていれば、曲を追加すると前の曲と重なる分だけ短く数える。
+func GetTrackByDurationWithPolicy(allTracks []model.Track, durationMs int, totalPlayTimeMs int, policy FitPolicy) []model.Track {
+	w := policy.window(totalPlayTimeMs)
+	// 曲の長さと残り時間の差として許容される範囲
+	minDiff := w.lo - totalPlayTimeMs
+	maxDiff := w.hi - totalPlayTimeMs
 
 	var bestTrack *model.Track
-	bestDiff := allowance + 1 // 許容誤差を超える初期値
+	bestDiff := -1
 
 	for i := range allTracks {
-		diff := abs(allTracks[i].DurationMs - durationMs)
-		// 許容誤差内かつ、これまでで最も近い曲を選択
-		if diff <= allowance && diff < bestDiff {
+		diff := allTracks[i].DurationMs - policy.CrossfadeMs - durationMs
+		if diff < minDiff || diff > maxDiff {
+			continue
+		}
+		// 許容範囲内かつ、これまでで最も近い曲を選択
+		if bestTrack == nil || abs(diff) < bestDiff {
 			bestTrack = &allTracks[i]
-			bestDiff = diff
+			bestDiff = abs(diff)
 			if diff == 0 {
 				break // 完全一致なら即終了
 			}
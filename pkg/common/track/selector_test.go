@@ -1,6 +1,9 @@
 package track
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/pp-develop/music-timer-api/model"
@@ -295,3 +298,294 @@ func TestAbs(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+// Selector のテスト
+// =============================================================================
+// Selector は全プロバイダ共通の選曲エンジン。
+// 以下のロジックをテストする:
+// 1. 組み合わせが見つかった場合 → 選曲結果と診断情報を返す
+// 2. 再生時間が足りない場合 → ErrNotEnoughTracks
+// 3. 再生時間は足りるが組み合わせがない場合 → ErrTimeoutCreatePlaylist
+// =============================================================================
+
+// TestSelector_Select は、選曲結果と診断情報が正しく返されることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3分, 2分, 4分のトラック
+//   - 要求: 5分（300000ms）
+//   - 期待結果: 合計5分、診断情報にプールの曲数と総再生時間が入る
+func TestSelector_Select(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "track1", DurationMs: 180000}, // 3分
+		{Uri: "track2", DurationMs: 120000}, // 2分
+		{Uri: "track3", DurationMs: 240000}, // 4分
+	}
+
+	result, err := NewSelector(Options{Source: "test"}).Select(context.Background(), tracks, 300000)
+
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if result.TotalMs != 300000 {
+		t.Errorf("Expected total duration 300000ms, got %d", result.TotalMs)
+	}
+	if result.Diagnostics.PoolSize != 3 {
+		t.Errorf("Expected pool size 3, got %d", result.Diagnostics.PoolSize)
+	}
+	if result.Diagnostics.PoolDurationMs != 540000 {
+		t.Errorf("Expected pool duration 540000ms, got %d", result.Diagnostics.PoolDurationMs)
+	}
+}
+
+// TestSelector_Classification は、失敗時のエラー分類が
+// 再生時間不足と組み合わせなしで区別されることをテストする。
+//
+// テストケース:
+//   - 合計5分のプールで30分を要求: ErrNotEnoughTracks
+//   - 3分×3曲のプールで5分を要求: ErrTimeoutCreatePlaylist
+func TestSelector_Classification(t *testing.T) {
+	tests := []struct {
+		name     string        // テストケースの説明
+		tracks   []model.Track // 候補プール
+		targetMs int           // 要求時間
+		expected error         // 期待されるエラー
+	}{
+		{
+			name: "not enough duration",
+			tracks: []model.Track{
+				{Uri: "track1", DurationMs: 180000},
+				{Uri: "track2", DurationMs: 120000},
+			},
+			targetMs: 30 * MillisecondsPerMinute,
+			expected: model.ErrNotEnoughTracks,
+		},
+		{
+			name: "no combination",
+			tracks: []model.Track{
+				{Uri: "track1", DurationMs: 180000},
+				{Uri: "track2", DurationMs: 180000},
+				{Uri: "track3", DurationMs: 180000},
+			},
+			targetMs: 300000,
+			expected: model.ErrTimeoutCreatePlaylist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSelector(Options{Source: "test"}).Select(context.Background(), tt.tracks, tt.targetMs)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// TestSelector_SameSeedSameResult は、同じ候補プールに同じシードを指定すると
+// 同じ選曲結果になることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 2分〜6分のトラック 300曲
+//   - 要求: 30分、シード 42 で2回選曲
+//   - 期待結果: 2回とも同じ曲が同じ順番で選ばれる
+//
+// ユーザーから報告されたプレイリストを再現できることを保証する。
+func TestSelector_SameSeedSameResult(t *testing.T) {
+	tracks := make([]model.Track, 300)
+	for i := range tracks {
+		tracks[i] = model.Track{
+			Uri:        fmt.Sprintf("track%d", i),
+			DurationMs: 120000 + (i*7919)%240000,
+		}
+	}
+
+	first, err := NewSelector(Options{Seed: 42}).Select(context.Background(), tracks, 30*MillisecondsPerMinute)
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	second, err := NewSelector(Options{Seed: 42}).Select(context.Background(), tracks, 30*MillisecondsPerMinute)
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+
+	if len(first.Tracks) != len(second.Tracks) {
+		t.Fatalf("Expected same track count, got %d and %d", len(first.Tracks), len(second.Tracks))
+	}
+	for i := range first.Tracks {
+		if first.Tracks[i].Uri != second.Tracks[i].Uri {
+			t.Errorf("Expected same track at %d, got %s and %s", i, first.Tracks[i].Uri, second.Tracks[i].Uri)
+		}
+	}
+}
+
+// =============================================================================
+// FitPolicy（許容誤差とモード）のテスト
+// =============================================================================
+// リクエストで指定された許容誤差とモードに従って
+// MakeTracksWithPolicy / GetTrackByDurationWithPolicy が曲を選ぶことをテストする。
+// =============================================================================
+
+// TestMakeTracksWithPolicy は、モードごとに許容される合計時間が変わることをテストする。
+//
+// テストケース:
+//   - under-only: 9分55秒 + 4分50秒 → 10分を超える曲は選ばない
+//   - over-only: 9分55秒では足りないため、10分以上になる曲で埋める
+//   - exact: 許容誤差を指定しても完全一致のみ
+//   - 許容誤差指定: 5分未満のプレイリストでも指定した誤差を許容する
+func TestMakeTracksWithPolicy(t *testing.T) {
+	tests := []struct {
+		name     string        // テストケースの説明
+		tracks   []model.Track // 入力トラック（この順で選ばれる）
+		targetMs int           // 要求時間
+		policy   FitPolicy     // 許容誤差とモード
+		success  bool          // 成功が期待されるか
+		minMs    int           // 合計時間の下限
+		maxMs    int           // 合計時間の上限
+	}{
+		{
+			name: "under-only never exceeds target",
+			tracks: []model.Track{
+				{Uri: "track1", DurationMs: 595000}, // 9分55秒
+				{Uri: "track2", DurationMs: 10000},  // 10秒（超過するので使えない）
+			},
+			targetMs: 600000,
+			policy:   FitPolicy{ToleranceMs: 15000, Mode: FitModeUnderOnly},
+			success:  true,
+			minMs:    585000,
+			maxMs:    600000,
+		},
+		{
+			name: "over-only never ends early",
+			tracks: []model.Track{
+				{Uri: "track1", DurationMs: 595000}, // 9分55秒
+				{Uri: "track2", DurationMs: 200000}, // 長すぎて使えない
+				{Uri: "track3", DurationMs: 8000},   // 8秒（合計10分3秒）
+			},
+			targetMs: 600000,
+			policy:   FitPolicy{ToleranceMs: 15000, Mode: FitModeOverOnly},
+			success:  true,
+			minMs:    600000,
+			maxMs:    615000,
+		},
+		{
+			name: "exact ignores tolerance",
+			tracks: []model.Track{
+				{Uri: "track1", DurationMs: 595000},
+			},
+			targetMs: 600000,
+			policy:   FitPolicy{ToleranceMs: 15000, Mode: FitModeExact},
+			success:  false,
+		},
+		{
+			name: "explicit tolerance for short playlist",
+			tracks: []model.Track{
+				{Uri: "track1", DurationMs: 180000}, // 3分
+				{Uri: "track2", DurationMs: 110000}, // 1分50秒
+			},
+			targetMs: 300000,
+			policy:   FitPolicy{ToleranceMs: 10000},
+			success:  true,
+			minMs:    290000,
+			maxMs:    310000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, result := MakeTracksWithPolicy(tt.tracks, tt.targetMs, tt.policy)
+			if success != tt.success {
+				t.Fatalf("Expected success=%v, got %v", tt.success, success)
+			}
+			if !success {
+				return
+			}
+			total := 0
+			for _, track := range result {
+				total += track.DurationMs
+			}
+			if total < tt.minMs || total > tt.maxMs {
+				t.Errorf("Expected total in [%d, %d], got %d", tt.minMs, tt.maxMs, total)
+			}
+		})
+	}
+}
+
+// TestGetTrackByDurationWithPolicy_UnderOnly は、under-only では
+// より近くても残り時間を超える曲が選ばれないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 1分2秒（2秒長い）, 55秒（5秒短い）のトラック
+//   - 検索: 1分（60000ms）、under-only、許容誤差15秒
+//   - 期待結果: 55秒のトラック
+func TestGetTrackByDurationWithPolicy_UnderOnly(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "track1", DurationMs: 62000}, // 1分2秒（2秒長い）
+		{Uri: "track2", DurationMs: 55000}, // 55秒（5秒短い）
+	}
+
+	result := GetTrackByDurationWithPolicy(tracks, 60000, 600000, FitPolicy{ToleranceMs: 15000, Mode: FitModeUnderOnly})
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 track, got %d", len(result))
+	}
+	if result[0].DurationMs != 55000 {
+		t.Errorf("Expected track with 55000ms, got %d", result[0].DurationMs)
+	}
+}
+
+// TestSelector_TrackLengthFilter は、minTrackMs / maxTrackMs の範囲外の曲が
+// 選ばれないことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 30秒のインタールード、3分の曲10曲、12分の曲
+//   - 要求: 12分、minTrackMs=60000, maxTrackMs=600000
+//   - 期待結果: 成功、3分の曲のみが選ばれる
+func TestSelector_TrackLengthFilter(t *testing.T) {
+	pool := []model.Track{
+		{Uri: "interlude", DurationMs: 30000},
+		{Uri: "epic", DurationMs: 720000},
+	}
+	for i := 0; i < 10; i++ {
+		pool = append(pool, model.Track{Uri: fmt.Sprintf("track%d", i), DurationMs: 180000})
+	}
+
+	result, err := NewSelector(Options{Seed: 1, MinTrackMs: 60000, MaxTrackMs: 600000}).Select(context.Background(), pool, 720000)
+
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	for _, track := range result.Tracks {
+		if track.DurationMs != 180000 {
+			t.Errorf("Track %s (%dms) should have been filtered out", track.Uri, track.DurationMs)
+		}
+	}
+	if result.Diagnostics.FilteredOut != 2 {
+		t.Errorf("Expected 2 filtered out, got %d", result.Diagnostics.FilteredOut)
+	}
+}
+
+// TestSelector_TrackLengthFilter_NotEnough は、フィルタ後のプールで要求時間を
+// 満たせない場合に、エラーの追加情報にその旨が含まれることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3分の曲2つ、12分の曲1つ
+//   - 要求: 12分、maxTrackMs=600000
+//   - 期待結果: ErrNotEnoughTracks、details.reason = "track_length_filter"
+func TestSelector_TrackLengthFilter_NotEnough(t *testing.T) {
+	pool := []model.Track{
+		{Uri: "track1", DurationMs: 180000},
+		{Uri: "track2", DurationMs: 180000},
+		{Uri: "epic", DurationMs: 720000},
+	}
+
+	_, err := NewSelector(Options{Seed: 1, MaxTrackMs: 600000}).Select(context.Background(), pool, 720000)
+
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Fatalf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+	details := model.ErrorDetails(err)
+	if details["reason"] != "track_length_filter" || details["filtered_out"] != 1 {
+		t.Errorf("Unexpected error details: %v", details)
+	}
+}
@@ -0,0 +1,251 @@
+package track
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"math/rand"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+const (
+	// 大規模プールで残り時間を厳密探索するときに使う候補曲数の上限
+	maxExactCandidates = 2048
+
+	// 厳密探索（DP）の計算量の上限。単位は「候補曲数 × 合計再生時間の上限（ミリ秒）」で、
+	// 各曲ごとに合計再生時間のビットセットをずらして重ねる演算のビット数にあたる。
+	// 2048曲 × 60分 相当で、1回あたり1億ワード演算程度に収まる。
+	// 曲数が少なければ60分を超える合計再生時間でも厳密探索する。
+	maxExactWork = maxExactCandidates * 60 * MillisecondsPerMinute
+
+	// 厳密探索で確保するメモリ（親テーブル uint16 + 到達可能ビットセット）の上限
+	// 64MB ≒ 合計再生時間 8時間50分
+	maxExactMemoryBytes = 64 << 20
+
+	// 大規模プールで前半をランダムに埋めた後、厳密探索に残す時間幅
+	// 20分 = 1200000ms
+	residualWindowMs = 20 * MillisecondsPerMinute
+
+	// 大規模プールでの試行回数の上限
+	maxSolveAttempts = 64
+)
+
+// window は合計再生時間として許容される範囲 [lo, hi] と目標値を表す
+type window struct {
+	target int
+	lo     int
+	hi     int
+}
+
+// shift は前半部分で消費した時間を差し引いた探索範囲を返す
+func (w window) shift(usedMs int) window {
+	return window{
+		target: w.target - usedMs,
+		lo:     w.lo - usedMs,
+		hi:     w.hi - usedMs,
+	}
+}
+
+// solve は候補プールから合計再生時間が w の範囲に入る組み合わせを探す。探索の試行回数もあわせて返す。
+//
+// DPの計算量が maxExactWork に、メモリが maxExactMemoryBytes に収まる規模では全組み合わせを調べるため、
+// 解があれば必ず見つかり、見つからない場合は組み合わせが存在しないことが確定する。
+// それより大きなプールではランダムに選んだ前半部分と、残り時間に対する厳密探索を
+// ctx の期限まで繰り返す。
+//
+// 戻り値のエラー:
+//   - model.ErrNotEnoughTracks: プール全体の再生時間が不足している
+//   - model.ErrTimeoutCreatePlaylist: 組み合わせが見つからない
+func solve(ctx context.Context, pool []model.Track, w window, rng *rand.Rand) ([]model.Track, int, error) {
+	// 再生時間が0以下、または単体で上限を超える曲は組み合わせに使えない
+	candidates := make([]model.Track, 0, len(pool))
+	totalDuration := 0
+	for _, t := range pool {
+		if t.DurationMs <= 0 || t.DurationMs > w.hi {
+			continue
+		}
+		candidates = append(candidates, t)
+		totalDuration += t.DurationMs
+	}
+
+	if w.hi < 0 || totalDuration < w.lo {
+		return nil, 0, model.ErrNotEnoughTracks
+	}
+
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	// 全組み合わせを調べられる規模なら1回の厳密探索で結論が出る
+	if exactTableFits(len(candidates), min(w.hi, totalDuration)) {
+		tracks, ok := solveExact(candidates, w, rng)
+		if !ok {
+			return nil, 1, model.ErrTimeoutCreatePlaylist
+		}
+		return tracks, 1, nil
+	}
+
+	attempt := 0
+	for ; attempt < maxSolveAttempts; attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+		if attempt > 0 {
+			rng.Shuffle(len(candidates), func(i, j int) {
+				candidates[i], candidates[j] = candidates[j], candidates[i]
+			})
+		}
+		if tracks, ok := solveWithPrefix(candidates, w, rng); ok {
+			return tracks, attempt + 1, nil
+		}
+	}
+
+	return nil, attempt, model.ErrTimeoutCreatePlaylist
+}
+
+// exactTableFits は n 曲で合計 hi までのDPが厳密探索の計算量とメモリの上限に収まるかを返す
+func exactTableFits(n, hi int) bool {
+	size := int64(hi) + 1
+	if n > math.MaxUint16 {
+		return false // 親テーブルに曲のインデックスを記録できない
+	}
+	return int64(n)*int64(hi) <= maxExactWork && size*2+size/8 <= maxExactMemoryBytes
+}
+
+// solveWithPrefix はシャッフル済みの候補の先頭から残り時間が residualWindowMs 程度になるまで
+// 曲を詰め、残り時間を後続の候補で厳密探索する
+func solveWithPrefix(candidates []model.Track, w window, rng *rand.Rand) ([]model.Track, bool) {
+	residual := residualWindowMs
+	if w.target < residual {
+		residual = w.target
+	}
+
+	var prefix []model.Track
+	var rest []model.Track
+	prefixMs := 0
+	for _, t := range candidates {
+		if prefixMs+t.DurationMs <= w.target-residual {
+			prefix = append(prefix, t)
+			prefixMs += t.DurationMs
+			continue
+		}
+		if len(rest) < maxExactCandidates {
+			rest = append(rest, t)
+		}
+	}
+
+	tail, ok := solveExact(rest, w.shift(prefixMs), rng)
+	if !ok {
+		return nil, false
+	}
+
+	tracks := append(prefix, tail...)
+	rng.Shuffle(len(tracks), func(i, j int) {
+		tracks[i], tracks[j] = tracks[j], tracks[i]
+	})
+	return tracks, true
+}
+
+// solveExact はビットセットによる部分和DPで、合計が w の範囲に入る組み合わせを探す。
+// 到達可能な合計値ごとに「最初にその値へ到達させた曲」を記録しておき、
+// 目標に最も近い合計値から逆順にたどって組み合わせを復元する。
+func solveExact(candidates []model.Track, w window, rng *rand.Rand) ([]model.Track, bool) {
+	if w.hi < 0 {
+		return nil, false
+	}
+	lo := w.lo
+	if lo < 0 {
+		lo = 0
+	}
+
+	// 候補の合計を超える合計値には到達しないので、テーブルは小さい方に合わせる
+	total := 0
+	for _, t := range candidates {
+		total += t.DurationMs
+	}
+	hi := min(w.hi, total)
+
+	size := hi + 1
+	reach := make([]uint64, (size+63)/64)
+	// parent[s] は合計 s に最初に到達した曲のインデックス+1（0は未到達）
+	parent := make([]uint16, size)
+	reach[0] = 1
+
+	for i, t := range candidates {
+		d := t.DurationMs
+		if d <= 0 || d >= size {
+			continue
+		}
+		q, r := d/64, uint(d%64)
+		// 上位ワードから処理することで、同じ曲を二重に使わない（0/1ナップサック）
+		for wi := len(reach) - 1; wi >= q; wi-- {
+			shifted := reach[wi-q] << r
+			if r != 0 && wi-q-1 >= 0 {
+				shifted |= reach[wi-q-1] >> (64 - r)
+			}
+			added := shifted &^ reach[wi]
+			if added == 0 {
+				continue
+			}
+			reach[wi] |= added
+			for added != 0 {
+				b := bits.TrailingZeros64(added)
+				added &= added - 1
+				if s := wi*64 + b; s < size {
+					parent[s] = uint16(i + 1)
+				}
+			}
+		}
+	}
+
+	best := -1
+	for diff := 0; ; diff++ {
+		under, over := w.target-diff, w.target+diff
+		if under < lo && over > hi {
+			break
+		}
+		underOK := under >= lo && under <= hi && isReachable(reach, under)
+		overOK := diff > 0 && over >= lo && over <= hi && isReachable(reach, over)
+		if underOK && overOK {
+			// 同じ誤差の候補が2つある場合はランダムに選ぶ
+			if rng.Intn(2) == 0 {
+				best = under
+			} else {
+				best = over
+			}
+			break
+		}
+		if underOK {
+			best = under
+			break
+		}
+		if overOK {
+			best = over
+			break
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+
+	tracks := make([]model.Track, 0)
+	for s := best; s > 0; {
+		idx := int(parent[s]) - 1
+		tracks = append(tracks, candidates[idx])
+		s -= candidates[idx].DurationMs
+	}
+
+	rng.Shuffle(len(tracks), func(i, j int) {
+		tracks[i], tracks[j] = tracks[j], tracks[i]
+	})
+	return tracks, true
+}
+
+// isReachable はビットセット上で合計 s に到達可能かを返す
+func isReachable(reach []uint64, s int) bool {
+	if s < 0 || s/64 >= len(reach) {
+		return false
+	}
+	return reach[s/64]&(1<<uint(s%64)) != 0
+}
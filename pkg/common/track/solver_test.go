@@ -0,0 +1,220 @@
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// solve 関数のテスト
+// =============================================================================
+// solve は候補プールから指定時間に合う組み合わせを部分和DPで探す関数。
+// 以下のロジックをテストする:
+// 1. 解が存在する場合は必ず見つかる（シャッフルの運に依存しない）
+// 2. 解が存在しない場合は組み合わせなしと判定される
+// 3. プール全体の再生時間が足りない場合は再生時間不足と判定される
+// 4. 大規模プールでもタイムアウトせずに解が見つかる
+// 5. 60分を超える指定時間でも、曲数が少なければ組み合わせがないことを1回の探索で確定する
+// =============================================================================
+
+// solveTarget は既定の許容誤差で targetMs に合う組み合わせを探すテスト用ヘルパー
+func solveTarget(ctx context.Context, pool []model.Track, targetMs int) ([]model.Track, error) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	tracks, _, err := solve(ctx, pool, DefaultFitPolicy(targetMs).window(targetMs), rng)
+	return tracks, err
+}
+
+// sumDuration は曲の合計再生時間を返すテスト用ヘルパー
+func sumDuration(tracks []model.Track) int {
+	total := 0
+	for _, t := range tracks {
+		total += t.DurationMs
+	}
+	return total
+}
+
+// TestSolve_FindsOnlyCombination は、解がただ1つしかない小さなプールで
+// 必ずその組み合わせが見つかることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3分1秒, 1分59秒, 1分, 4分7秒, 2分13秒のトラック
+//   - 要求: 5分（300000ms）→ 3分1秒 + 1分59秒 のみが一致
+//   - 期待結果: 成功、合計5分ちょうど
+//
+// 従来のシャッフル＆リトライでは運が悪いとタイムアウトしていたケース。
+func TestSolve_FindsOnlyCombination(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "track1", DurationMs: 181000}, // 3分1秒
+		{Uri: "track2", DurationMs: 119000}, // 1分59秒
+		{Uri: "track3", DurationMs: 60000},  // 1分
+		{Uri: "track4", DurationMs: 247000}, // 4分7秒
+		{Uri: "track5", DurationMs: 133000}, // 2分13秒
+	}
+
+	for i := 0; i < 20; i++ {
+		result, err := solveTarget(context.Background(), tracks, 300000)
+		if err != nil {
+			t.Fatalf("Expected combination to be found, got error: %v", err)
+		}
+		if total := sumDuration(result); total != 300000 {
+			t.Fatalf("Expected total duration 300000ms, got %d", total)
+		}
+	}
+}
+
+// TestSolve_NoCombination は、再生時間は足りているが
+// 組み合わせが存在しない場合に ErrTimeoutCreatePlaylist が返ることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3分, 3分, 3分のトラック（合計9分）
+//   - 要求: 5分（300000ms、10分未満なので完全一致のみ）
+//   - 期待結果: ErrTimeoutCreatePlaylist（組み合わせなし）
+func TestSolve_NoCombination(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "track1", DurationMs: 180000},
+		{Uri: "track2", DurationMs: 180000},
+		{Uri: "track3", DurationMs: 180000},
+	}
+
+	_, err := solveTarget(context.Background(), tracks, 300000)
+
+	if !errors.Is(err, model.ErrTimeoutCreatePlaylist) {
+		t.Errorf("Expected ErrTimeoutCreatePlaylist, got %v", err)
+	}
+}
+
+// TestSolve_NotEnoughDuration は、プール全体の再生時間が
+// 要求時間に満たない場合に ErrNotEnoughTracks が返ることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3分, 2分のトラック（合計5分）
+//   - 要求: 30分
+//   - 期待結果: ErrNotEnoughTracks
+func TestSolve_NotEnoughDuration(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "track1", DurationMs: 180000},
+		{Uri: "track2", DurationMs: 120000},
+	}
+
+	_, err := solveTarget(context.Background(), tracks, 30*MillisecondsPerMinute)
+
+	if !errors.Is(err, model.ErrNotEnoughTracks) {
+		t.Errorf("Expected ErrNotEnoughTracks, got %v", err)
+	}
+}
+
+// TestSolve_WithinAllowance は、10分以上のプレイリストで
+// 許容誤差内の組み合わせが受け入れられることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 5分, 4分50秒のトラック（合計9分50秒）
+//   - 要求: 10分（誤差10秒、許容誤差15秒以内）
+//   - 期待結果: 成功
+func TestSolve_WithinAllowance(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "track1", DurationMs: 300000},
+		{Uri: "track2", DurationMs: 290000},
+	}
+
+	result, err := solveTarget(context.Background(), tracks, 600000)
+
+	if err != nil {
+		t.Fatalf("Expected success within allowance, got error: %v", err)
+	}
+	if total := sumDuration(result); abs(total-600000) > AllowanceMs {
+		t.Errorf("Expected total within allowance, got %d", total)
+	}
+}
+
+// TestSolve_LargePool は、グローバルカタログ規模のプールで
+// 長時間のプレイリストが短時間で作成できることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 2分〜6分のランダムなトラック 20000曲
+//   - 要求: 90分（厳密探索の上限60分を超えるため前半ランダム + 残り厳密探索）
+//   - 期待結果: 成功、許容誤差内、重複なし
+func TestSolve_LargePool(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tracks := make([]model.Track, 20000)
+	for i := range tracks {
+		tracks[i] = model.Track{
+			Uri:        fmt.Sprintf("track%d", i),
+			DurationMs: 120000 + r.Intn(240000),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(DefaultTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	target := 90 * MillisecondsPerMinute
+	result, err := solveTarget(ctx, tracks, target)
+
+	if err != nil {
+		t.Fatalf("Expected success for large pool, got error: %v", err)
+	}
+	if total := sumDuration(result); abs(total-target) > AllowanceMs {
+		t.Errorf("Expected total within allowance, got %d", total)
+	}
+
+	seen := make(map[string]bool)
+	for _, track := range result {
+		if seen[track.Uri] {
+			t.Fatalf("Duplicate track selected: %s", track.Uri)
+		}
+		seen[track.Uri] = true
+	}
+}
+
+// TestSolve_LongTimerSmallPoolIsExact は、60分を超える指定時間でも曲数が少なければ
+// 厳密探索で組み合わせがないことを確定することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 3分のトラック 40曲（合計120分）
+//   - 要求: 91分30秒（3分の倍数の90分・93分とは許容誤差を超えて離れる）
+//   - 期待結果: ErrTimeoutCreatePlaylist、試行回数1回（ランダムな探索を繰り返さない）
+func TestSolve_LongTimerSmallPoolIsExact(t *testing.T) {
+	tracks := make([]model.Track, 40)
+	for i := range tracks {
+		tracks[i] = model.Track{Uri: fmt.Sprintf("track%d", i), DurationMs: 180000}
+	}
+
+	target := 91*MillisecondsPerMinute + 30000
+	rng := rand.New(rand.NewSource(1))
+	_, attempts, err := solve(context.Background(), tracks, DefaultFitPolicy(target).window(target), rng)
+
+	if !errors.Is(err, model.ErrTimeoutCreatePlaylist) {
+		t.Errorf("Expected ErrTimeoutCreatePlaylist, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single exact search, got %d attempts", attempts)
+	}
+}
+
+// TestExactTableFits は厳密探索を行う規模の判定をテストする。
+//
+// テストシナリオ:
+//   - 2048曲 × 60分 → 厳密探索する（従来の上限）
+//   - 100曲 × 8時間 → 厳密探索する（曲数が少ないので60分を超えても収まる）
+//   - 2048曲 × 90分、100曲 × 24時間（メモリの上限を超える） → 厳密探索しない
+func TestExactTableFits(t *testing.T) {
+	tests := []struct {
+		n, hi int
+		want  bool
+	}{
+		{2048, 60 * MillisecondsPerMinute, true},
+		{100, 8 * 60 * MillisecondsPerMinute, true},
+		{2048, 90 * MillisecondsPerMinute, false},
+		{100, 24 * 60 * MillisecondsPerMinute, false},
+	}
+	for _, tt := range tests {
+		if got := exactTableFits(tt.n, tt.hi); got != tt.want {
+			t.Errorf("exactTableFits(%d, %d) = %v, want %v", tt.n, tt.hi, got, tt.want)
+		}
+	}
+}
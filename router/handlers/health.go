@@ -4,9 +4,20 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/spotify/json"
 )
 
 // HealthCheck returns the health status of the API
 func HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "UP"})
 }
+
+// ReadinessCheck reports ready only once a track catalog version is loaded
+func ReadinessCheck(c *gin.Context) {
+	version := json.LoadedVersion()
+	if version == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "NOT_READY"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "READY", "catalog_version": version})
+}
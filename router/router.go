@@ -41,6 +41,7 @@ func setupRoutes(router *gin.Engine) {
 
 	// Health check
 	api.GET("/health", handlers.HealthCheck)
+	api.GET("/ready", handlers.ReadinessCheck)
 
 	// Spotify API endpoints
 	spotify := api.Group("/spotify")
@@ -75,6 +76,8 @@ func setupRoutes(router *gin.Engine) {
 		{
 			tracks.POST("", spotifyHandlers.SaveTracks)
 			tracks.POST("/reset", spotifyHandlers.ResetTracks)
+			tracks.POST("/rollback", spotifyHandlers.RollbackTracks)
+			tracks.GET("/coverage", spotifyHandlers.GetCatalogCoverage)
 			tracks.GET("/favorites/exists", spotifyHandlers.GetFavoriteTracksExists)
 
 			// Track initialization endpoints
@@ -97,6 +100,19 @@ func setupRoutes(router *gin.Engine) {
 			playlists.POST("/guest", spotifyHandlers.GestCreatePlaylist)
 			playlists.POST("/from-favorites", spotifyHandlers.CreatePlaylistFromFavorites)
 			playlists.POST("/from-artists", spotifyHandlers.CreatePlaylistFromArtists)
+			playlists.POST("/intervals", spotifyHandlers.CreateIntervalPlaylist)
+			playlists.POST("/mix", spotifyHandlers.CreateMixPlaylist)
+			playlists.POST("/preview", spotifyHandlers.PreviewPlaylist)
+			playlists.POST("/commit", spotifyHandlers.CommitPlaylist)
+			playlists.POST("/candidates", spotifyHandlers.GetPlaylistCandidates)
+		}
+
+		// Blocklist endpoints
+		blocklist := spotify.Group("/blocklist")
+		{
+			blocklist.GET("", spotifyHandlers.GetBlocklist)
+			blocklist.POST("", spotifyHandlers.AddBlocklist)
+			blocklist.DELETE("/:type/:id", spotifyHandlers.DeleteBlocklist)
 		}
 	}
 
@@ -152,6 +168,19 @@ func setupRoutes(router *gin.Engine) {
 			playlists.DELETE("", soundcloudHandlers.DeletePlaylistsSoundCloud)
 			playlists.POST("/from-favorites", soundcloudHandlers.CreatePlaylistFromFavorites)
 			playlists.POST("/from-artists", soundcloudHandlers.CreatePlaylistFromArtists)
+			playlists.POST("/intervals", soundcloudHandlers.CreateIntervalPlaylist)
+			playlists.POST("/mix", soundcloudHandlers.CreateMixPlaylist)
+			playlists.POST("/preview", soundcloudHandlers.PreviewPlaylistSoundCloud)
+			playlists.POST("/commit", soundcloudHandlers.CommitPlaylistSoundCloud)
+			playlists.POST("/candidates", soundcloudHandlers.GetPlaylistCandidatesSoundCloud)
+		}
+
+		// Blocklist endpoints
+		blocklist := soundcloud.Group("/blocklist")
+		{
+			blocklist.GET("", soundcloudHandlers.GetBlocklistSoundCloud)
+			blocklist.POST("", soundcloudHandlers.AddBlocklistSoundCloud)
+			blocklist.DELETE("/:type/:id", soundcloudHandlers.DeleteBlocklistSoundCloud)
 		}
 	}
 }
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/blocklist"
+)
+
+// GetBlocklistSoundCloud returns the tracks and artists the user has excluded from selection
+func GetBlocklistSoundCloud(c *gin.Context) {
+	items, err := blocklist.List(c, model.ServiceSoundCloud)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// AddBlocklistSoundCloud adds tracks or artists to the user's blocklist
+func AddBlocklistSoundCloud(c *gin.Context) {
+	items, err := blocklist.Add(c, model.ServiceSoundCloud)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"items": items})
+}
+
+// DeleteBlocklistSoundCloud removes a track or artist from the user's blocklist
+func DeleteBlocklistSoundCloud(c *gin.Context) {
+	err := blocklist.Remove(c, model.ServiceSoundCloud)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
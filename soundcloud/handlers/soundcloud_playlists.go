@@ -13,26 +13,86 @@ import (
 
 // CreatePlaylistFromFavorites creates a SoundCloud playlist from user's favorite tracks
 func CreatePlaylistFromFavorites(c *gin.Context) {
-	playlistId, secretToken, err := playlist.CreatePlaylistFromFavorites(c)
+	response, err := playlist.CreatePlaylistFromFavorites(c)
 	if err != nil {
 		slog.Error("error creating playlist from favorites", slog.Any("error", err))
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"playlist_id": playlistId, "secret_token": secretToken})
+	c.JSON(http.StatusCreated, response)
 }
 
 // CreatePlaylistFromArtists creates a SoundCloud playlist from specified artists
 func CreatePlaylistFromArtists(c *gin.Context) {
-	playlistId, secretToken, err := playlist.CreatePlaylistFromArtists(c)
+	response, err := playlist.CreatePlaylistFromArtists(c)
 	if err != nil {
 		slog.Error("error creating playlist from artists", slog.Any("error", err))
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"playlist_id": playlistId, "secret_token": secretToken})
+	c.JSON(http.StatusCreated, response)
+}
+
+// CreateIntervalPlaylist creates a SoundCloud playlist made of independently filled segments
+func CreateIntervalPlaylist(c *gin.Context) {
+	response, err := playlist.CreateIntervalPlaylist(c)
+	if err != nil {
+		slog.Error("error creating interval playlist", slog.Any("error", err))
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// CreateMixPlaylist creates a SoundCloud playlist mixing several sources by percentage weights
+func CreateMixPlaylist(c *gin.Context) {
+	response, err := playlist.CreateMixPlaylist(c)
+	if err != nil {
+		slog.Error("error creating mix playlist", slog.Any("error", err))
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// PreviewPlaylistSoundCloud selects tracks without creating a playlist and returns a preview token
+func PreviewPlaylistSoundCloud(c *gin.Context) {
+	response, err := playlist.PreviewPlaylist(c)
+	if err != nil {
+		slog.Error("error previewing playlist", slog.Any("error", err))
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CommitPlaylistSoundCloud creates a SoundCloud playlist from a previously previewed track list
+func CommitPlaylistSoundCloud(c *gin.Context) {
+	response, err := playlist.CommitPlaylist(c)
+	if err != nil {
+		slog.Error("error committing playlist", slog.Any("error", err))
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetPlaylistCandidatesSoundCloud returns several alternative track sets, each with a preview token
+func GetPlaylistCandidatesSoundCloud(c *gin.Context) {
+	response, err := playlist.GetPlaylistCandidates(c)
+	if err != nil {
+		slog.Error("error selecting playlist candidates", slog.Any("error", err))
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetPlaylistsSoundCloud retrieves user's SoundCloud playlists
@@ -0,0 +1,87 @@
+package playlist
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/preview"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/soundcloud/auth"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+// Number of candidates returned when count is omitted
+const defaultCandidateCount = 3
+
+type PlaylistCandidatesRequest struct {
+	PreviewPlaylistRequest
+	Count int `json:"count" binding:"omitempty,min=1,max=10"`
+}
+
+// GetPlaylistCandidates returns several candidates with distinct track sets, selected from a pool loaded once.
+// Each candidate is saved as a preview; passing its token to CommitPlaylist creates a playlist with those tracks.
+func GetPlaylistCandidates(c *gin.Context) (*model.PlaylistCandidatesResponse, error) {
+	var json PlaylistCandidatesRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		slog.Error("failed to bind JSON", slog.Any("error", err))
+		return nil, err
+	}
+
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	if json.Source == commontrack.SegmentSourceArtists && len(json.ArtistIds) == 0 {
+		return nil, model.ErrInvalidRequest
+	}
+	count := json.Count
+	if count == 0 {
+		count = defaultCandidateCount
+	}
+
+	// Get authenticated user
+	user, err := auth.GetAuth(c)
+	if err != nil {
+		slog.Error("authentication failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		slog.Error("failed to get DB instance")
+		return nil, model.ErrFailedGetDB
+	}
+
+	opts, err := prepareOptions(dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("selecting playlist candidates", slog.String("source", json.Source), slog.Int("duration_ms", specifyMs), slog.Int("count", count), slog.Int64("seed", opts.Seed))
+
+	pool, err := getPool(dbInstance, user, json.Source, json.ArtistIds)
+	if err != nil {
+		slog.Error("failed to get tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	opts.Source = "soundcloud/" + json.Source
+	candidates, err := commontrack.SelectCandidates(c.Request.Context(), pool, specifyMs, count, opts)
+	if err != nil {
+		slog.Error("failed to select candidates", slog.Any("error", err))
+		return nil, err
+	}
+
+	return preview.SaveCandidates(dbInstance, model.ServiceSoundCloud, user.Id, specifyMs, candidates)
+}
+
+// getPool returns the same candidate pool CreatePlaylistFrom* uses for the given source
+func getPool(db *sql.DB, user *model.SoundCloudUser, source string, artistIds []string) ([]model.Track, error) {
+	switch source {
+	case commontrack.SegmentSourceArtists:
+		return getArtistsPool(db, user.AccessToken, artistIds)
+	default:
+		return getFavoritesPool(db, user.Id)
+	}
+}
@@ -0,0 +1,107 @@
+package playlist
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/soundcloud/auth"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+type CreateIntervalPlaylistRequest struct {
+	commontrack.Params
+	commontrack.IntervalParams
+}
+
+// CreateIntervalPlaylist creates a SoundCloud playlist made of independently filled segments
+// (e.g. Pomodoro work/break cycles). The global catalog is not available on SoundCloud.
+func CreateIntervalPlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
+	var json CreateIntervalPlaylistRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		slog.Error("failed to bind JSON", slog.Any("error", err))
+		return nil, err
+	}
+
+	segments, segmentMs, err := json.Expand()
+	if err != nil {
+		return nil, err
+	}
+	// Pinned tracks cannot be assigned to a particular segment
+	if len(json.Pinned) > 0 {
+		return nil, model.WithDetails(model.ErrInvalidPinnedTracks, map[string]interface{}{
+			"reason": "not_supported",
+		})
+	}
+
+	// Get authenticated user
+	user, err := auth.GetAuth(c)
+	if err != nil {
+		slog.Error("authentication failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		slog.Error("failed to get DB instance")
+		return nil, model.ErrFailedGetDB
+	}
+
+	opts, err := prepareOptions(dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("creating interval playlist", slog.Int("segment_count", len(segments)), slog.Int64("seed", opts.Seed))
+
+	// Fill each segment from its own source
+	opts.Source = "soundcloud/intervals"
+	tracks, boundaries, err := commontrack.FillSegments(c.Request.Context(), segments, segmentMs, opts, func(seg commontrack.Segment) ([]model.Track, error) {
+		return getSegmentPool(dbInstance, user.AccessToken, user.Id, seg)
+	})
+	if err != nil {
+		slog.Error("failed to get interval tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Title with the requested total rather than the actual played length
+	totalMs := 0
+	for _, ms := range segmentMs {
+		totalMs += ms
+	}
+	title := "Interval Playlist " + commontrack.FormatDuration(totalMs)
+	description := fmt.Sprintf("Generated interval playlist with %d segments", len(boundaries))
+	playlist, err := materialize(dbInstance, user, tracks, title, description)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.CreatePlaylistResponse{
+		PlaylistID:  strconv.Itoa(playlist.ID),
+		SecretToken: playlist.SecretToken,
+		Seed:        opts.Seed,
+		Segments:    boundaries,
+		DeltaMs:     commontrack.SegmentsDeltaMs(boundaries),
+		Details:     commontrack.SegmentsDetails(boundaries),
+	}
+	if json.Debug {
+		response.Diagnostics = commontrack.SegmentsDiagnostics(boundaries)
+	}
+	return response, nil
+}
+
+// getSegmentPool returns the candidate pool for a segment's source
+func getSegmentPool(db *sql.DB, accessToken string, userId string, seg commontrack.Segment) ([]model.Track, error) {
+	switch seg.Source {
+	case commontrack.SegmentSourceFavorites:
+		return getFavoritesPool(db, userId)
+	case commontrack.SegmentSourceArtists:
+		return getArtistsPool(db, accessToken, seg.ArtistIds)
+	default:
+		// SoundCloud has no global catalog
+		return nil, model.ErrInvalidSegment
+	}
+}
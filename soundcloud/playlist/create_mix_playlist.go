@@ -0,0 +1,93 @@
+package playlist
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/soundcloud/auth"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+type CreateMixPlaylistRequest struct {
+	commontrack.Params
+	commontrack.Duration
+	commontrack.MixParams
+}
+
+// CreateMixPlaylist creates a SoundCloud playlist mixing favorites and followed artists by percentage weights.
+// The global catalog is not available on SoundCloud.
+func CreateMixPlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
+	var json CreateMixPlaylistRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		slog.Error("failed to bind JSON", slog.Any("error", err))
+		return nil, err
+	}
+
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	if json.Weights.Catalog > 0 {
+		return nil, model.WithDetails(model.ErrInvalidRequest, map[string]interface{}{
+			"reason": "not_supported",
+			"param":  "weights.catalog",
+		})
+	}
+	parts, err := json.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get authenticated user
+	user, err := auth.GetAuth(c)
+	if err != nil {
+		slog.Error("authentication failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		slog.Error("failed to get DB instance")
+		return nil, model.ErrFailedGetDB
+	}
+
+	opts, err := prepareOptions(dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("creating mix playlist", slog.Int("duration_ms", specifyMs), slog.Any("weights", json.Weights), slog.Int64("seed", opts.Seed))
+
+	// Select from each source according to its weight
+	opts.Source = "soundcloud/mix"
+	result, shares, err := commontrack.FillMix(c.Request.Context(), parts, specifyMs, opts, func(seg commontrack.Segment) ([]model.Track, error) {
+		return getSegmentPool(dbInstance, user.AccessToken, user.Id, seg)
+	})
+	if err != nil {
+		slog.Error("failed to get mix tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	title := "Mix Playlist " + commontrack.FormatDuration(specifyMs)
+	description := fmt.Sprintf("Generated mix playlist for %s (favorites %d%%, artists %d%%)", commontrack.FormatDuration(specifyMs), json.Weights.Favorites, json.Weights.FollowedArtists)
+	playlist, err := materialize(dbInstance, user, result.Tracks, title, description)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.CreatePlaylistResponse{
+		PlaylistID:  strconv.Itoa(playlist.ID),
+		SecretToken: playlist.SecretToken,
+		Seed:        opts.Seed,
+		DeltaMs:     result.TotalMs - specifyMs,
+		Details:     result.Approximation.Details(),
+		Shares:      shares,
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
+	}
+	return response, nil
+}
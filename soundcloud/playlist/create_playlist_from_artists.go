@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pp-develop/music-timer-api/api/soundcloud"
@@ -18,86 +17,85 @@ import (
 )
 
 type CreatePlaylistFromArtistsRequest struct {
-	Minute    int      `json:"minute" binding:"required,min=1"`
+	commontrack.Params
+	commontrack.Duration
 	ArtistIds []string `json:"artistIds" binding:"required,min=1"`
 }
 
 // CreatePlaylistFromArtists creates a SoundCloud playlist from specified artists' tracks
-// Returns playlistID, secretToken, and error
-func CreatePlaylistFromArtists(c *gin.Context) (string, string, error) {
+func CreatePlaylistFromArtists(c *gin.Context) (*model.CreatePlaylistResponse, error) {
 	var json CreatePlaylistFromArtistsRequest
 	if err := c.ShouldBindJSON(&json); err != nil {
 		slog.Error("failed to bind JSON", slog.Any("error", err))
-		return "", "", err
+		return nil, err
 	}
 
-	slog.Info("creating playlist from artists", slog.Int("duration_minutes", json.Minute), slog.Any("artist_ids", json.ArtistIds))
-
-	// Convert minutes to milliseconds
-	specifyMs := json.Minute * commontrack.MillisecondsPerMinute
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
 
 	// Get authenticated user
 	user, err := auth.GetAuth(c)
 	if err != nil {
 		slog.Error("authentication failed", slog.Any("error", err))
-		return "", "", err
+		return nil, err
 	}
 
 	dbInstance, ok := utils.GetDB(c)
 	if !ok {
 		slog.Error("failed to get DB instance")
-		return "", "", model.ErrFailedGetDB
+		return nil, model.ErrFailedGetDB
 	}
 
-	// Get tracks from specified artists (DB first, then API fallback)
-	tracks, err := getTracksFromArtists(dbInstance, user.AccessToken, specifyMs, json.ArtistIds)
+	opts, err := prepareOptions(dbInstance, user, json.Params)
 	if err != nil {
-		slog.Error("failed to get tracks", slog.Any("error", err))
-		return "", "", err
-	}
-
-	if len(tracks) == 0 {
-		slog.Error("no tracks available for playlist creation")
-		return "", "", model.ErrNotEnoughTracks
+		return nil, err
 	}
+	slog.Info("creating playlist from artists", slog.Int("duration_ms", specifyMs), slog.Any("artist_ids", json.ArtistIds), slog.Int64("seed", opts.Seed))
 
-	// Extract track IDs
-	trackIDs := make([]string, len(tracks))
-	for i, track := range tracks {
-		trackIDs[i] = track.ID
+	// Get tracks from specified artists (DB first, then API fallback)
+	result, err := getTracksFromArtists(dbInstance, user.AccessToken, specifyMs, json.ArtistIds, opts)
+	if err != nil {
+		slog.Error("failed to get tracks", slog.Any("error", err))
+		return nil, err
 	}
 
-	// Create playlist on SoundCloud with tracks included
-	client := soundcloud.NewClient()
-	title := fmt.Sprintf("Playlist %d min", json.Minute)
-	description := fmt.Sprintf("Generated playlist for %d minutes from artists", json.Minute)
-
-	playlist, err := client.CreatePlaylist(user.AccessToken, title, description, trackIDs)
+	title := "Playlist " + commontrack.FormatDuration(specifyMs)
+	description := fmt.Sprintf("Generated playlist for %s from artists", commontrack.FormatDuration(specifyMs))
+	playlist, err := materialize(dbInstance, user, result.Tracks, title, description)
 	if err != nil {
-		slog.Error("failed to create playlist", slog.Any("error", err))
-		return "", "", err
+		return nil, err
 	}
 
-	// Save playlist to database
-	playlistID := strconv.Itoa(playlist.ID)
-	err = database.SaveSoundCloudPlaylist(dbInstance, playlistID, user.Id)
-	if err != nil {
-		slog.Error("failed to save playlist to database", slog.Any("error", err))
-		return "", "", err
+	response := &model.CreatePlaylistResponse{
+		PlaylistID:  strconv.Itoa(playlist.ID),
+		SecretToken: playlist.SecretToken,
+		Seed:        opts.Seed,
+		DeltaMs:     result.TotalMs - specifyMs,
+		Details:     result.Approximation.Details(),
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
 	}
+	return response, nil
+}
 
-	// Increment playlist count
-	err = database.IncrementSoundCloudPlaylistCount(dbInstance, user.Id)
+// getTracksFromArtists selects a combination that fits the requested duration from the artists' tracks
+func getTracksFromArtists(db *sql.DB, accessToken string, specifyMs int, artistIds []string, opts commontrack.Options) (*commontrack.Result, error) {
+	allTracks, err := getArtistsPool(db, accessToken, artistIds)
 	if err != nil {
-		slog.Warn("failed to increment playlist count", slog.Any("error", err))
+		return nil, err
 	}
 
-	slog.Info("playlist created successfully", slog.String("playlist_id", playlistID), slog.String("secret_token", playlist.SecretToken), slog.Int("tracks", len(trackIDs)))
-	return playlistID, playlist.SecretToken, nil
+	// Search for a combination that fits the requested duration
+	opts.Source = "soundcloud/artists"
+	opts.LogAttrs = []slog.Attr{slog.Int("artist_count", len(artistIds))}
+	return commontrack.NewSelector(opts).Select(context.Background(), allTracks, specifyMs)
 }
 
-// getTracksFromArtists fetches tracks from DB cache first, then API fallback
-func getTracksFromArtists(db *sql.DB, accessToken string, specifyMs int, artistIds []string) ([]model.Track, error) {
+// getArtistsPool fetches tracks from DB cache first, then API fallback
+func getArtistsPool(db *sql.DB, accessToken string, artistIds []string) ([]model.Track, error) {
 	var allTracks []model.Track
 	trackIDSet := make(map[string]bool)
 
@@ -138,74 +136,5 @@ func getTracksFromArtists(db *sql.DB, accessToken string, specifyMs int, artistI
 	}
 
 	slog.Info("found tracks from artists", slog.Int("track_count", len(allTracks)), slog.Int("artist_count", len(artistIds)))
-
-	// Calculate total available duration
-	totalDuration := 0
-	for _, track := range allTracks {
-		totalDuration += track.DurationMs
-	}
-
-	// Retry mechanism with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(commontrack.DefaultTimeoutSeconds)*time.Second)
-	defer cancel()
-
-	tracksChan := make(chan []model.Track, 1)
-	errChan := make(chan error, 1)
-	tryCountChan := make(chan int, 1)
-	tryCount := 0
-
-	go func() {
-		defer close(tracksChan)
-		defer close(errChan)
-		defer func() {
-			tryCountChan <- tryCount
-			close(tryCountChan)
-		}()
-
-		var tracks []model.Track
-		success := false
-		for !success {
-			select {
-			case <-ctx.Done():
-				errChan <- ctx.Err()
-				return
-			default:
-				tryCount++
-				shuffled := shuffleTracks(allTracks)
-				success, tracks = commontrack.MakeTracks(shuffled, specifyMs)
-			}
-		}
-		tracksChan <- tracks
-	}()
-
-	select {
-	case tracks := <-tracksChan:
-		<-tryCountChan
-		return tracks, nil
-	case err := <-errChan:
-		<-tryCountChan
-		return nil, err
-	case <-ctx.Done():
-		finalTryCount := <-tryCountChan
-
-		hasEnoughDuration := totalDuration >= specifyMs
-
-		if !hasEnoughDuration {
-			slog.Warn("timeout: not enough tracks",
-				slog.Int("required_minutes", specifyMs/commontrack.MillisecondsPerMinute),
-				slog.Int("available_minutes", totalDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(allTracks)),
-				slog.Int("try_count", finalTryCount),
-			)
-			return nil, model.ErrNotEnoughTracks
-		} else {
-			slog.Warn("timeout: combination not found",
-				slog.Int("duration_minutes", specifyMs/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(allTracks)),
-				slog.Int("total_duration_minutes", totalDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("try_count", finalTryCount),
-			)
-			return nil, model.ErrTimeoutCreatePlaylist
-		}
-	}
+	return allTracks, nil
 }
@@ -0,0 +1,90 @@
+package playlist
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+
+	"github.com/pp-develop/music-timer-api/api/soundcloud"
+	"github.com/pp-develop/music-timer-api/database"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/blocklist"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// prepareOptions builds the selection options from the request parameters,
+// resolving pinned tracks and loading the user's blocklist and recent playlist tracks
+func prepareOptions(db *sql.DB, user *model.SoundCloudUser, params commontrack.Params) (commontrack.Options, error) {
+	opts := params.Options()
+
+	// SoundCloud has no global catalog to fill the gap from
+	if params.FillFromCatalog {
+		return opts, model.WithDetails(model.ErrInvalidRequest, map[string]interface{}{
+			"reason": "not_supported",
+			"param":  "fillFromCatalog",
+		})
+	}
+
+	// Resolve pinned tracks so their durations can be subtracted from the target
+	var err error
+	opts.Pins, err = resolvePinned(user.AccessToken, params.Pinned)
+	if err != nil {
+		slog.Error("failed to resolve pinned tracks", slog.Any("error", err))
+		return opts, err
+	}
+
+	// Exclude tracks and artists the user has blocked
+	opts.Blocklist, err = blocklist.Load(db, model.ServiceSoundCloud, user.Id)
+	if err != nil {
+		slog.Error("failed to load blocklist", slog.Any("error", err))
+		return opts, err
+	}
+
+	// Avoid tracks used in the user's recent playlists
+	if params.AvoidsRecent() {
+		opts.Recent, err = database.GetRecentSoundCloudPlaylistTracks(db, user.Id, params.AvoidRecent, params.FreshnessDays)
+		if err != nil {
+			slog.Error("failed to load recent playlist tracks", slog.Any("error", err))
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// materialize creates the playlist on SoundCloud with the selected tracks and saves it to the database
+func materialize(db *sql.DB, user *model.SoundCloudUser, tracks []model.Track, title, description string) (*soundcloud.SCPlaylist, error) {
+	if len(tracks) == 0 {
+		slog.Error("no tracks available for playlist creation")
+		return nil, model.ErrNotEnoughTracks
+	}
+
+	// Extract track IDs
+	trackIDs := make([]string, len(tracks))
+	for i, track := range tracks {
+		trackIDs[i] = track.ID
+	}
+
+	// Create playlist on SoundCloud with tracks included
+	client := soundcloud.NewClient()
+	playlist, err := client.CreatePlaylist(user.AccessToken, title, description, trackIDs)
+	if err != nil {
+		slog.Error("failed to create playlist", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Save playlist to database
+	playlistID := strconv.Itoa(playlist.ID)
+	err = database.SaveSoundCloudPlaylist(db, playlistID, user.Id, tracks)
+	if err != nil {
+		slog.Error("failed to save playlist to database", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Increment playlist count (non-fatal)
+	if err = database.IncrementSoundCloudPlaylistCount(db, user.Id); err != nil {
+		slog.Warn("failed to increment playlist count", slog.Any("error", err))
+	}
+
+	slog.Info("playlist created successfully", slog.String("playlist_id", playlistID), slog.String("secret_token", playlist.SecretToken), slog.Int("tracks", len(trackIDs)))
+	return playlist, nil
+}
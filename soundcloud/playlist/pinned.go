@@ -0,0 +1,31 @@
+package playlist
+
+import (
+	"github.com/pp-develop/music-timer-api/api/soundcloud"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// resolvePinned fetches the pinned tracks from SoundCloud so their durations can be
+// subtracted from the target
+func resolvePinned(accessToken string, pinned []commontrack.PinnedTrack) ([]commontrack.Pin, error) {
+	if len(pinned) == 0 {
+		return nil, nil
+	}
+
+	client := soundcloud.NewClient()
+	var tracks []model.Track
+	for _, p := range pinned {
+		track, err := client.GetTrack(accessToken, p.Uri)
+		if err != nil {
+			return nil, err
+		}
+		if track != nil {
+			tracks = append(tracks, *track)
+		}
+	}
+
+	return commontrack.ResolvePins(pinned, tracks, func(t model.Track) string {
+		return t.ID
+	})
+}
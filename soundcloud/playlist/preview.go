@@ -0,0 +1,122 @@
+package playlist
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/preview"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/soundcloud/auth"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+type PreviewPlaylistRequest struct {
+	commontrack.Params
+	commontrack.Duration
+	// Source selects the candidate pool (favorites: /from-favorites, artists: /from-artists)
+	Source    string   `json:"source" binding:"required,oneof=favorites artists"`
+	ArtistIds []string `json:"artistIds"`
+}
+
+// PreviewPlaylist runs the same selection as CreatePlaylistFrom* without creating a playlist on SoundCloud.
+// Passing the returned token to CommitPlaylist creates a playlist with exactly those tracks.
+func PreviewPlaylist(c *gin.Context) (*model.PreviewPlaylistResponse, error) {
+	var json PreviewPlaylistRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		slog.Error("failed to bind JSON", slog.Any("error", err))
+		return nil, err
+	}
+
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	if json.Source == commontrack.SegmentSourceArtists && len(json.ArtistIds) == 0 {
+		return nil, model.ErrInvalidRequest
+	}
+
+	// Get authenticated user
+	user, err := auth.GetAuth(c)
+	if err != nil {
+		slog.Error("authentication failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		slog.Error("failed to get DB instance")
+		return nil, model.ErrFailedGetDB
+	}
+
+	opts, err := prepareOptions(dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("previewing playlist", slog.String("source", json.Source), slog.Int("duration_ms", specifyMs), slog.Int64("seed", opts.Seed))
+
+	result, err := selectTracks(dbInstance, user, json.Source, specifyMs, json.ArtistIds, opts)
+	if err != nil {
+		slog.Error("failed to get tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	response, err := preview.Save(dbInstance, model.ServiceSoundCloud, user.Id, specifyMs, opts.Seed, result)
+	if err != nil {
+		return nil, err
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
+	}
+	return response, nil
+}
+
+// CommitPlaylist creates a SoundCloud playlist with exactly the previewed tracks
+func CommitPlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
+	var json preview.CommitRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		slog.Error("failed to bind JSON", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Get authenticated user
+	user, err := auth.GetAuth(c)
+	if err != nil {
+		slog.Error("authentication failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		slog.Error("failed to get DB instance")
+		return nil, model.ErrFailedGetDB
+	}
+
+	return preview.Commit(dbInstance, json.PreviewToken, model.ServiceSoundCloud, user.Id, func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+		title := "Playlist " + commontrack.FormatDuration(p.TargetMs)
+		description := fmt.Sprintf("Generated playlist for %s", commontrack.FormatDuration(p.TargetMs))
+		playlist, err := materialize(dbInstance, user, p.Tracks, title, description)
+		if err != nil {
+			return nil, err
+		}
+		return &model.CreatePlaylistResponse{
+			PlaylistID:  strconv.Itoa(playlist.ID),
+			SecretToken: playlist.SecretToken,
+			Seed:        p.Seed,
+			DeltaMs:     p.TotalMs() - p.TargetMs,
+		}, nil
+	})
+}
+
+// selectTracks selects tracks from the given source the same way CreatePlaylistFrom* does
+func selectTracks(db *sql.DB, user *model.SoundCloudUser, source string, specifyMs int, artistIds []string, opts commontrack.Options) (*commontrack.Result, error) {
+	switch source {
+	case commontrack.SegmentSourceArtists:
+		return getTracksFromArtists(db, user.AccessToken, specifyMs, artistIds, opts)
+	default:
+		return getTracksFromFavorites(db, specifyMs, user.Id, opts)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/blocklist"
+)
+
+// GetBlocklist returns the tracks and artists the user has excluded from selection
+func GetBlocklist(c *gin.Context) {
+	items, err := blocklist.List(c, model.ServiceSpotify)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"items": items})
+}
+
+// AddBlocklist adds tracks or artists to the user's blocklist
+func AddBlocklist(c *gin.Context) {
+	items, err := blocklist.Add(c, model.ServiceSpotify)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, gin.H{"items": items})
+}
+
+// DeleteBlocklist removes a track or artist from the user's blocklist
+func DeleteBlocklist(c *gin.Context) {
+	err := blocklist.Remove(c, model.ServiceSpotify)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
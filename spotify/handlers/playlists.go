@@ -19,22 +19,22 @@ func GetPlaylists(c *gin.Context) {
 
 // CreatePlaylist creates a new playlist
 func CreatePlaylist(c *gin.Context) {
-	playlistId, err := playlist.CreatePlaylist(c)
+	response, err := playlist.CreatePlaylist(c)
 	if err != nil {
 		c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusCreated, playlistId)
+	c.IndentedJSON(http.StatusCreated, response)
 }
 
 // GestCreatePlaylist creates a guest playlist
 func GestCreatePlaylist(c *gin.Context) {
-	playlistId, err := playlist.GestCreatePlaylist(c)
+	response, err := playlist.GestCreatePlaylist(c)
 	if err != nil {
 		c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusCreated, playlistId)
+	c.IndentedJSON(http.StatusCreated, response)
 }
 
 // DeletePlaylists deletes the user's playlists
@@ -49,20 +49,70 @@ func DeletePlaylists(c *gin.Context) {
 
 // CreatePlaylistFromFavorites creates a playlist from user's favorite tracks
 func CreatePlaylistFromFavorites(c *gin.Context) {
-	playlistId, err := playlist.CreatePlaylistFromFavorites(c)
+	response, err := playlist.CreatePlaylistFromFavorites(c)
 	if err != nil {
 		c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusCreated, playlistId)
+	c.IndentedJSON(http.StatusCreated, response)
 }
 
 // CreatePlaylistFromArtists creates a playlist from specified artists' tracks
 func CreatePlaylistFromArtists(c *gin.Context) {
-	playlistId, err := playlist.CreatePlaylistFromArtists(c)
+	response, err := playlist.CreatePlaylistFromArtists(c)
 	if err != nil {
 		c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusCreated, playlistId)
+	c.IndentedJSON(http.StatusCreated, response)
+}
+
+// CreateIntervalPlaylist creates a playlist made of independently filled segments
+func CreateIntervalPlaylist(c *gin.Context) {
+	response, err := playlist.CreateIntervalPlaylist(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, response)
+}
+
+// PreviewPlaylist selects tracks without creating a playlist and returns a preview token
+func PreviewPlaylist(c *gin.Context) {
+	response, err := playlist.PreviewPlaylist(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// CommitPlaylist creates a playlist from a previously previewed track list
+func CommitPlaylist(c *gin.Context) {
+	response, err := playlist.CommitPlaylist(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, response)
+}
+
+// GetPlaylistCandidates returns several alternative track sets, each with a preview token
+func GetPlaylistCandidates(c *gin.Context) {
+	response, err := playlist.GetPlaylistCandidates(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// CreateMixPlaylist creates a playlist mixing several sources by percentage weights
+func CreateMixPlaylist(c *gin.Context) {
+	response, err := playlist.CreateMixPlaylist(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, response)
 }
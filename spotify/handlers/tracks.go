@@ -56,6 +56,41 @@ func ResetTracks(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// RollbackTracks switches the track catalog back to the previous version
+func RollbackTracks(c *gin.Context) {
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		c.Error(model.ErrFailedGetDB)
+		return
+	}
+
+	manifest, err := json.Rollback(dbInstance)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"version":      manifest.Version,
+		"total_tracks": manifest.TotalTracks,
+	})
+}
+
+// GetCatalogCoverage returns how many tracks and minutes the catalog has per market
+func GetCatalogCoverage(c *gin.Context) {
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		c.Error(model.ErrFailedGetDB)
+		return
+	}
+
+	coverage, err := json.Coverage(dbInstance)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, coverage)
+}
+
 // GetFavoriteTracksExists checks if favorite tracks exist for the user
 func GetFavoriteTracksExists(c *gin.Context) {
 	dbInstance, ok := utils.GetDB(c)
@@ -0,0 +1,365 @@
+package json
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// バイナリ形式のトラックファイル。
+// JSON より小さく、デコードせずに読めるため、選曲時はこちらを読む（JSON はエクスポート用に残す）。
+//
+// レイアウト（数値はすべてリトルエンディアン）:
+//
+//	ヘッダ     magic "MTC1" | version uint16 | reserved uint16 | recordCount uint32 |
+//	           bucketMs uint32 | bucketCount uint32 | stringTableSize uint32
+//	インデックス [bucketCount+1]uint32 … 区間 i（再生時間 [i×bucketMs, (i+1)×bucketMs)）の最初のレコード番号。
+//	           最後の区間は上限なし。最後の要素は recordCount
+//	レコード    [recordCount] durationMs uint32 | uriOffset uint32 | isrcOffset uint32 | uriLen uint16 | isrcLen uint16
+//	           （durationMs の昇順）
+//	文字列表    URI と ISRC を連結したバイト列
+const binaryFileNamePattern = "tracks_part_%d.bin"
+
+const (
+	binaryMagic      = "MTC1"
+	binaryVersion    = 1
+	binaryHeaderSize = 24
+	binaryRecordSize = 16
+
+	// インデックスの区間幅（1秒）と区間数（20分以上は最後の区間にまとめる）
+	binaryBucketMs    = 1000
+	binaryBucketCount = 20*60 + 1
+
+	// 一度に読み込むレコード数
+	binaryReadChunk = 4096
+)
+
+// writeTracksToBinaryFile はトラックを再生時間順に並べてバイナリ形式で書き込む
+func writeTracksToBinaryFile(filePath string, tracks []model.Track) error {
+	sorted := make([]model.Track, len(tracks))
+	copy(sorted, tracks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].DurationMs < sorted[j].DurationMs
+	})
+
+	// 区間ごとの最初のレコード番号と、文字列表の大きさを求める
+	index := make([]uint32, binaryBucketCount+1)
+	stringTableSize := 0
+	for _, t := range sorted {
+		if len(t.Uri) > 0xFFFF || len(t.Isrc) > 0xFFFF {
+			return fmt.Errorf("track %s has too long uri or isrc", t.Uri)
+		}
+		index[binaryBucket(t.DurationMs)+1]++
+		stringTableSize += len(t.Uri) + len(t.Isrc)
+	}
+	for i := 1; i < len(index); i++ {
+		index[i] += index[i-1]
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+
+	header := make([]byte, binaryHeaderSize)
+	copy(header[0:4], binaryMagic)
+	binary.LittleEndian.PutUint16(header[4:6], binaryVersion)
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(sorted)))
+	binary.LittleEndian.PutUint32(header[12:16], binaryBucketMs)
+	binary.LittleEndian.PutUint32(header[16:20], binaryBucketCount)
+	binary.LittleEndian.PutUint32(header[20:24], uint32(stringTableSize))
+	if _, err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := binary.Write(writer, binary.LittleEndian, index); err != nil {
+		return err
+	}
+
+	record := make([]byte, binaryRecordSize)
+	offset := 0
+	for _, t := range sorted {
+		binary.LittleEndian.PutUint32(record[0:4], uint32(t.DurationMs))
+		binary.LittleEndian.PutUint32(record[4:8], uint32(offset))
+		binary.LittleEndian.PutUint32(record[8:12], uint32(offset+len(t.Uri)))
+		binary.LittleEndian.PutUint16(record[12:14], uint16(len(t.Uri)))
+		binary.LittleEndian.PutUint16(record[14:16], uint16(len(t.Isrc)))
+		if _, err := writer.Write(record); err != nil {
+			return err
+		}
+		offset += len(t.Uri) + len(t.Isrc)
+	}
+
+	for _, t := range sorted {
+		if _, err := writer.WriteString(t.Uri); err != nil {
+			return err
+		}
+		if _, err := writer.WriteString(t.Isrc); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// binaryBucket は再生時間が含まれるインデックスの区間を返す
+func binaryBucket(durationMs int) int {
+	bucket := durationMs / binaryBucketMs
+	if bucket < 0 {
+		return 0
+	}
+	if bucket >= binaryBucketCount {
+		return binaryBucketCount - 1
+	}
+	return bucket
+}
+
+// binaryTrackFile は開いたバイナリ形式のトラックファイル
+type binaryTrackFile struct {
+	file            *os.File
+	recordCount     int
+	bucketMs        int
+	index           []uint32
+	recordsOffset   int64
+	stringsOffset   int64
+	stringTableSize int
+}
+
+// openBinaryTrackFile はバイナリ形式のトラックファイルを開き、ヘッダとインデックスを読み込む
+func openBinaryTrackFile(filePath string) (*binaryTrackFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := readBinaryHeader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("invalid binary track file %s: %w", filePath, err)
+	}
+	return f, nil
+}
+
+// readBinaryHeader はヘッダとインデックスを読み込んで検証する
+func readBinaryHeader(file *os.File) (*binaryTrackFile, error) {
+	header := make([]byte, binaryHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, err
+	}
+	if string(header[0:4]) != binaryMagic {
+		return nil, errors.New("bad magic")
+	}
+	if v := binary.LittleEndian.Uint16(header[4:6]); v != binaryVersion {
+		return nil, fmt.Errorf("unsupported version %d", v)
+	}
+
+	f := &binaryTrackFile{
+		file:            file,
+		recordCount:     int(binary.LittleEndian.Uint32(header[8:12])),
+		bucketMs:        int(binary.LittleEndian.Uint32(header[12:16])),
+		stringTableSize: int(binary.LittleEndian.Uint32(header[20:24])),
+	}
+	bucketCount := int(binary.LittleEndian.Uint32(header[16:20]))
+	if f.bucketMs <= 0 || bucketCount <= 0 {
+		return nil, errors.New("bad index")
+	}
+
+	f.index = make([]uint32, bucketCount+1)
+	if err := binary.Read(file, binary.LittleEndian, f.index); err != nil {
+		return nil, err
+	}
+	if int(f.index[bucketCount]) != f.recordCount {
+		return nil, errors.New("index does not match record count")
+	}
+	f.recordsOffset = int64(binaryHeaderSize + 4*len(f.index))
+	f.stringsOffset = f.recordsOffset + int64(binaryRecordSize*f.recordCount)
+
+	// 書き込み途中のファイルを読まないよう、ファイルの大きさを確かめる
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() != f.stringsOffset+int64(f.stringTableSize) {
+		return nil, errors.New("truncated file")
+	}
+	return f, nil
+}
+
+// Close はファイルを閉じる
+func (f *binaryTrackFile) Close() error {
+	return f.file.Close()
+}
+
+// recordRange は再生時間が [minMs, maxMs] に含まれうるレコードの範囲 [first, last) をインデックスから求める。
+// minMs, maxMs が0の場合はその側を制限しない。
+func (f *binaryTrackFile) recordRange(minMs, maxMs int) (int, int) {
+	buckets := len(f.index) - 1
+	first, last := 0, f.recordCount
+	if minMs > 0 {
+		if b := minMs / f.bucketMs; b < buckets {
+			first = int(f.index[b])
+		} else {
+			first = int(f.index[buckets-1])
+		}
+	}
+	if maxMs > 0 {
+		if b := maxMs/f.bucketMs + 1; b < buckets {
+			last = int(f.index[b])
+		}
+	}
+	return first, last
+}
+
+// exactRange は再生時間が [minMs, maxMs] のレコードの範囲 [first, last) を返す。
+// インデックスで区間を絞り込み、境界の区間の中はレコードの再生時間を二分探索する。
+func (f *binaryTrackFile) exactRange(minMs, maxMs int) (int, int, error) {
+	first, last := f.recordRange(minMs, maxMs)
+	if first >= last {
+		return first, first, nil
+	}
+
+	var err error
+	search := func(lo, hi int, pred func(durationMs int) bool) int {
+		return lo + sort.Search(hi-lo, func(i int) bool {
+			if err != nil {
+				return true
+			}
+			var durationMs int
+			durationMs, err = f.durationAt(lo + i)
+			return pred(durationMs)
+		})
+	}
+	if minMs > 0 {
+		first = search(first, last, func(d int) bool { return d >= minMs })
+	}
+	if maxMs > 0 {
+		last = search(first, last, func(d int) bool { return d > maxMs })
+	}
+	return first, last, err
+}
+
+// durationAt は i 番目のレコードの再生時間を読む
+func (f *binaryTrackFile) durationAt(i int) (int, error) {
+	b := make([]byte, 4)
+	if _, err := f.file.ReadAt(b, f.recordsOffset+int64(binaryRecordSize*i)); err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint32(b)), nil
+}
+
+// binaryRecord はバイナリ形式のファイルのレコード1件
+type binaryRecord struct {
+	durationMs int
+	uriOffset  int
+	isrcOffset int
+	uriLen     int
+	isrcLen    int
+}
+
+// readRecords は start 番目から n 件のレコードを読む。buf は読み込みに使うバッファ（n 件分以上）。
+func (f *binaryTrackFile) readRecords(start, n int, buf []byte) ([]binaryRecord, error) {
+	chunk := buf[:binaryRecordSize*n]
+	if _, err := f.file.ReadAt(chunk, f.recordsOffset+int64(binaryRecordSize*start)); err != nil {
+		return nil, err
+	}
+	records := make([]binaryRecord, n)
+	for i := range records {
+		b := chunk[binaryRecordSize*i : binaryRecordSize*(i+1)]
+		records[i] = binaryRecord{
+			durationMs: int(binary.LittleEndian.Uint32(b[0:4])),
+			uriOffset:  int(binary.LittleEndian.Uint32(b[4:8])),
+			isrcOffset: int(binary.LittleEndian.Uint32(b[8:12])),
+			uriLen:     int(binary.LittleEndian.Uint16(b[12:14])),
+			isrcLen:    int(binary.LittleEndian.Uint16(b[14:16])),
+		}
+	}
+	return records, nil
+}
+
+// emitTracks は records の曲を fn に渡す。
+// 文字列表は records が参照する範囲だけを読む（連続したレコードの文字列は連続して書かれている）。
+func (f *binaryTrackFile) emitTracks(records []binaryRecord, fn func(model.Track)) error {
+	if len(records) == 0 {
+		return nil
+	}
+	lo, hi := f.stringTableSize, 0
+	for _, r := range records {
+		lo = min(lo, r.uriOffset, r.isrcOffset)
+		hi = max(hi, r.uriOffset+r.uriLen, r.isrcOffset+r.isrcLen)
+	}
+	if hi > f.stringTableSize {
+		return errors.New("string offset out of range")
+	}
+
+	strings := make([]byte, hi-lo)
+	if _, err := f.file.ReadAt(strings, f.stringsOffset+int64(lo)); err != nil {
+		return err
+	}
+	for _, r := range records {
+		fn(model.Track{
+			Uri:        string(strings[r.uriOffset-lo : r.uriOffset-lo+r.uriLen]),
+			DurationMs: r.durationMs,
+			Isrc:       string(strings[r.isrcOffset-lo : r.isrcOffset-lo+r.isrcLen]),
+		})
+	}
+	return nil
+}
+
+// each は再生時間が [minMs, maxMs] の曲を再生時間の昇順に fn に渡す。
+// インデックスで該当するレコードの位置まで直接移動し、その範囲のレコードと文字列だけを読む。
+func (f *binaryTrackFile) each(minMs, maxMs int, fn func(model.Track)) error {
+	first, last, err := f.exactRange(minMs, maxMs)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, binaryRecordSize*binaryReadChunk)
+	for start := first; start < last; start += binaryReadChunk {
+		records, err := f.readRecords(start, min(binaryReadChunk, last-start), buf)
+		if err != nil {
+			return err
+		}
+		if err := f.emitTracks(records, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachAt は positions 番目（昇順）のレコードの曲を fn に渡す。
+// 抽出する曲だけを読むため、読み込む量はファイルの大きさではなく曲数に比例する。
+// binaryReadChunk 件の範囲に収まる位置はまとめて、レコードと文字列をそれぞれ1回で読む。
+func (f *binaryTrackFile) eachAt(positions []int, fn func(model.Track)) error {
+	buf := make([]byte, binaryRecordSize*binaryReadChunk)
+	selected := make([]binaryRecord, 0, min(len(positions), binaryReadChunk))
+	for start := 0; start < len(positions); {
+		first := positions[start]
+		end := start
+		for ; end < len(positions) && positions[end]-first < binaryReadChunk; end++ {
+			if positions[end] < 0 || positions[end] >= f.recordCount || (end > start && positions[end] <= positions[end-1]) {
+				return errors.New("record position out of range")
+			}
+		}
+
+		records, err := f.readRecords(first, positions[end-1]-first+1, buf)
+		if err != nil {
+			return err
+		}
+		selected = selected[:0]
+		for _, i := range positions[start:end] {
+			selected = append(selected, records[i-first])
+		}
+		if err := f.emitTracks(selected, fn); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package json
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// バイナリ形式のトラックファイルのテスト
+// =============================================================================
+// writeTracksToBinaryFile と eachTrackInFile が以下を満たすことをテストする:
+// 1. 書き込んだ曲の URI・ISRC・再生時間をそのまま読める（アーティストは保存しない）
+// 2. 再生時間の範囲を指定すると、インデックスの区間の境界でも範囲内の曲だけを返す
+// 3. 書き込み途中（大きさが合わない）のファイルは読まない
+// 4. eachAt は指定した位置の曲だけを、まとめて読む範囲をまたいでも昇順に返す
+// =============================================================================
+
+// writeBinaryTestFile は tracks をバイナリ形式で一時ディレクトリに書き込み、パスを返すテスト用ヘルパー
+func writeBinaryTestFile(t *testing.T, tracks []model.Track) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tracks_part_1.bin")
+	if err := writeTracksToBinaryFile(path, tracks); err != nil {
+		t.Fatalf("writeTracksToBinaryFile() unexpected error: %v", err)
+	}
+	return path
+}
+
+// readBinaryTestFile は再生時間が [minMs, maxMs] の曲を読むテスト用ヘルパー
+func readBinaryTestFile(t *testing.T, path string, minMs, maxMs int) []model.Track {
+	t.Helper()
+	var tracks []model.Track
+	if err := eachTrackInFile(trackFile{binary: path}, minMs, maxMs, func(track model.Track) {
+		tracks = append(tracks, track)
+	}); err != nil {
+		t.Fatalf("eachTrackInFile() unexpected error: %v", err)
+	}
+	return tracks
+}
+
+// TestBinaryTrackFile_RoundTrip は、書き込んだ曲を読み戻せることをテストする。
+//
+// テストシナリオ:
+//   - 入力: 再生時間順でない4曲（ISRCなしの曲、20分を超える曲、アーティスト付きの曲を含む）
+//   - 期待結果: 再生時間の昇順に4曲、URI・ISRC・再生時間が一致、アーティストは空
+func TestBinaryTrackFile_RoundTrip(t *testing.T) {
+	tracks := []model.Track{
+		{Uri: "spotify:track:c", DurationMs: 240000, Isrc: "GBXYZ2400001"},
+		{Uri: "spotify:track:long", DurationMs: 25 * 60 * 1000, Isrc: "USABC9900001"},
+		{Uri: "spotify:track:a", DurationMs: 180000, Isrc: "JPABC2400001", ArtistsId: []string{"artist1"}},
+		{Uri: "spotify:track:no-isrc", DurationMs: 200000},
+	}
+	want := []model.Track{tracks[2], tracks[3], tracks[0], tracks[1]}
+
+	got := readBinaryTestFile(t, writeBinaryTestFile(t, tracks), 0, 0)
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d tracks, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Uri != want[i].Uri || got[i].Isrc != want[i].Isrc || got[i].DurationMs != want[i].DurationMs {
+			t.Errorf("Track %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+		if len(got[i].ArtistsId) != 0 {
+			t.Errorf("Track %d: artists are not stored, got %v", i, got[i].ArtistsId)
+		}
+	}
+}
+
+// TestBinaryTrackFile_RangeEdges は、再生時間の範囲の境界が区間（1秒）の途中にある場合に、
+// 範囲内の曲だけを返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 59.999秒〜120.001秒の境界付近の曲と、20分を超える曲
+//   - 期待結果: 各範囲で、境界の値を含めて範囲内の曲だけを返す
+func TestBinaryTrackFile_RangeEdges(t *testing.T) {
+	durations := []int{59999, 60000, 60001, 60500, 60999, 61000, 61001, 119999, 120000, 120001, 1500000, 1500001}
+	tracks := make([]model.Track, len(durations))
+	for i, d := range durations {
+		tracks[i] = model.Track{Uri: "track" + string(rune('a'+i)), DurationMs: d, Isrc: "JPABC2400001"}
+	}
+	path := writeBinaryTestFile(t, tracks)
+
+	tests := []struct {
+		name         string
+		minMs, maxMs int
+	}{
+		{"both edges on bucket boundaries", 60000, 120000},
+		{"both edges inside a bucket", 60001, 60999},
+		{"single value", 61000, 61000},
+		{"max only", 0, 60000},
+		{"min only inside a bucket", 60500, 0},
+		{"min in the last bucket", 1500001, 0},
+		{"empty range inside a bucket", 60600, 60900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []int
+			for _, d := range durations {
+				if (tt.minMs == 0 || d >= tt.minMs) && (tt.maxMs == 0 || d <= tt.maxMs) {
+					want = append(want, d)
+				}
+			}
+
+			got := readBinaryTestFile(t, path, tt.minMs, tt.maxMs)
+
+			if len(got) != len(want) {
+				t.Fatalf("Expected %v, got %d tracks", want, len(got))
+			}
+			for i, track := range got {
+				if track.DurationMs != want[i] {
+					t.Errorf("Track %d: expected %d, got %d", i, want[i], track.DurationMs)
+				}
+				if track.Uri != tracks[indexOf(durations, want[i])].Uri || track.Isrc != "JPABC2400001" {
+					t.Errorf("Track %d: unexpected uri or isrc %+v", i, track)
+				}
+			}
+		})
+	}
+}
+
+// TestBinaryTrackFile_EachAt は、指定した位置のレコードの曲だけを返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: binaryReadChunk の2倍を超える曲数のファイルと、まとめて読む範囲の境界をまたぐ位置
+//   - 期待結果: 指定した位置の曲だけを昇順に返す。範囲外や昇順でない位置はエラー
+func TestBinaryTrackFile_EachAt(t *testing.T) {
+	n := binaryReadChunk*2 + 100
+	tracks := make([]model.Track, n)
+	for i := range tracks {
+		tracks[i] = model.Track{Uri: fmt.Sprintf("spotify:track:%d", i), DurationMs: 60000 + i, Isrc: fmt.Sprintf("JPABC%07d", i)}
+	}
+	bf, err := openBinaryTrackFile(writeBinaryTestFile(t, tracks))
+	if err != nil {
+		t.Fatalf("openBinaryTrackFile() unexpected error: %v", err)
+	}
+	defer bf.Close()
+
+	positions := []int{0, 1, binaryReadChunk - 1, binaryReadChunk, binaryReadChunk + 7, binaryReadChunk * 2, n - 1}
+	var got []model.Track
+	if err := bf.eachAt(positions, func(track model.Track) { got = append(got, track) }); err != nil {
+		t.Fatalf("eachAt() unexpected error: %v", err)
+	}
+
+	if len(got) != len(positions) {
+		t.Fatalf("Expected %d tracks, got %d", len(positions), len(got))
+	}
+	for i, p := range positions {
+		if got[i].Uri != tracks[p].Uri || got[i].Isrc != tracks[p].Isrc || got[i].DurationMs != tracks[p].DurationMs {
+			t.Errorf("Track %d: expected %+v, got %+v", i, tracks[p], got[i])
+		}
+	}
+
+	for _, invalid := range [][]int{{-1}, {n}, {5, 3}, {2, 2}} {
+		if err := bf.eachAt(invalid, func(model.Track) {}); err == nil {
+			t.Errorf("eachAt(%v) expected error, got nil", invalid)
+		}
+	}
+}
+
+// TestBinaryTrackFile_Truncated は、大きさが合わないファイルを読まないことをテストする。
+func TestBinaryTrackFile_Truncated(t *testing.T) {
+	path := writeBinaryTestFile(t, testTracks("track", 10))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := eachTrackInFile(trackFile{binary: path}, 0, 0, func(model.Track) {}); err == nil {
+		t.Error("Expected error for a truncated file")
+	}
+}
+
+func indexOf(values []int, v int) int {
+	for i, x := range values {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
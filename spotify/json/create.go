@@ -2,11 +2,13 @@ package json
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
@@ -59,15 +61,11 @@ var (
 	cacheInitialized     bool
 )
 
-// ClearFilesExistCache はファイル存在キャッシュをクリアする（ReCreate時に呼び出す）
-func ClearFilesExistCache() {
-	filesExistCacheMutex.Lock()
-	defer filesExistCacheMutex.Unlock()
-	cacheInitialized = false
-}
+// buildMutex はカタログの作成（Create / ReCreate）と Rollback を直列にする
+var buildMutex sync.Mutex
 
-// deleteAllTrackFiles は既存のトラックファイルを全て削除する（ReCreate時に呼び出す）
-func deleteAllTrackFiles() error {
+// deleteLegacyTrackFiles はバージョン管理の導入前に baseDirectory 直下に作られたトラックファイルを削除する
+func deleteLegacyTrackFiles() error {
 	for i := 1; ; i++ {
 		filePath := fmt.Sprintf("%s/%s", baseDirectory, fmt.Sprintf(fileNamePattern, i))
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -76,7 +74,7 @@ func deleteAllTrackFiles() error {
 		if err := os.Remove(filePath); err != nil {
 			return fmt.Errorf("failed to delete %s: %w", filePath, err)
 		}
-		slog.Info("deleted file", slog.String("path", filePath))
+		slog.Info("deleted legacy file", slog.String("path", filePath))
 	}
 	return nil
 }
@@ -89,6 +87,22 @@ func setFilesExistCache(exists bool) {
 	cacheInitialized = true
 }
 
+// LoadedVersion は選曲に使うカタログのバージョンを返す。まだ読み込んでいなければ空文字を返す。
+func LoadedVersion() string {
+	filesExistCacheMutex.RLock()
+	loaded := cacheInitialized && filesExistCache
+	filesExistCacheMutex.RUnlock()
+	if !loaded {
+		return ""
+	}
+
+	v, err := currentVersion()
+	if err != nil || v == nil {
+		return ""
+	}
+	return v.manifest.Version
+}
+
 func exist() (bool, error) {
 	filesExistCacheMutex.RLock()
 	if cacheInitialized {
@@ -119,105 +133,139 @@ func exist() (bool, error) {
 	return result, nil
 }
 
-// checkFilesExist は実際にファイルの存在をチェックする（動的ファイル数対応）
+// checkFilesExist は current が指すバージョンがあり、曲が含まれているかをチェックする
 func checkFilesExist() (bool, error) {
-	// 最低1ファイルは必要
-	firstFilePath := fmt.Sprintf("%s/%s", baseDirectory, fmt.Sprintf(fileNamePattern, 1))
-	if _, err := os.Stat(firstFilePath); os.IsNotExist(err) {
-		slog.Debug("first file does not exist", slog.String("path", firstFilePath))
+	v, err := currentVersion()
+	if err != nil {
+		return false, err
+	}
+	if v == nil {
+		slog.Debug("catalog version does not exist", slog.String("path", currentLink))
+		return false, nil
+	}
+	if v.manifest.TotalTracks == 0 {
+		slog.Warn("tracks are empty in catalog version", slog.String("version", v.manifest.Version))
 		return false, nil
 	}
+	return true, nil
+}
 
-	// 存在するファイルをすべてチェック
-	for i := 1; ; i++ {
-		filePath := fmt.Sprintf("%s/%s", baseDirectory, fmt.Sprintf(fileNamePattern, i))
+// createJson はDBの曲から新しいバージョンのトラックファイル一式を作成し、検証できたら current を差し替える。
+// 途中で失敗した場合は作成中のバージョンを削除し、current はそれまでのバージョンのままにする。
+func createJson(db *sql.DB) (*Manifest, error) {
+	start := time.Now()
+	slog.Info("create json started", slog.String("memory", getMemStats()))
 
-		file, err := os.Open(filePath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				// ファイルが存在しない = 全ファイルチェック完了
-				if i == 1 {
-					return false, nil // 1つもない
-				}
-				break // i-1個のファイルが存在
-			}
-			return false, err
-		}
+	version := newVersionName()
+	v := &catalogVersion{
+		dir:      filepath.Join(versionsDirectory, version),
+		manifest: &Manifest{Version: version, Markets: make(map[string]*MarketPartition)},
+	}
+	if err := os.MkdirAll(v.dir, os.ModePerm); err != nil {
+		return nil, err
+	}
 
-		var config struct {
-			Tracks []struct{} `json:"tracks"`
+	if err := writeVersion(db, v); err != nil {
+		if rmErr := os.RemoveAll(v.dir); rmErr != nil {
+			slog.Warn("failed to delete incomplete catalog version", slog.String("version", version), slog.Any("error", rmErr))
 		}
+		return nil, err
+	}
 
-		decoder := json.NewDecoder(file)
-		err = decoder.Decode(&config)
-		file.Close()
+	// 切り替えた後は旧形式のファイルを読まないので削除する
+	if err := deleteLegacyTrackFiles(); err != nil {
+		slog.Warn("failed to delete legacy files", slog.Any("error", err))
+	}
 
-		if err != nil {
-			slog.Error("error decoding file", slog.String("path", filePath), slog.Any("error", err))
-			return false, err
-		}
+	slog.Info("create json completed",
+		slog.String("version", version),
+		slog.Int("files", v.manifest.Parts),
+		slog.Int("total_tracks", v.manifest.TotalTracks),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("memory", getMemStats()))
 
-		if len(config.Tracks) == 0 {
-			slog.Warn("tracks are empty in file", slog.String("path", filePath))
-			return false, nil
-		}
-	}
-	return true, nil
+	return v.manifest, nil
 }
 
-func createJson(db *sql.DB) error {
-	start := time.Now()
-	slog.Info("create json started", slog.String("memory", getMemStats()))
-
+// writeVersion はバージョンのディレクトリにトラックファイルと manifest を書き込み、検証して current を差し替える
+func writeVersion(db *sql.DB, v *catalogVersion) error {
 	// 1ファイルあたりのトラック数
 	// メモリ効率のため5万件（約5MB）を上限として分割
 	const tracksPerFile = 50000
 
-	// ディレクトリの存在確認
-	if _, err := os.Stat(baseDirectory); os.IsNotExist(err) {
-		if err := os.MkdirAll(baseDirectory, os.ModePerm); err != nil {
+	// uri の順に1ファイル分ずつ取得する（メモリ効率化）。
+	// キーセットページネーションで取得するため、ファイル同士で曲が重複することも漏れることもない。
+	pageNumber := 0
+	err := database.ExportTracks(db, tracksPerFile, func(tracks []model.Track) error {
+		pageNumber++
+		if err := writeShard(v, pageNumber, tracks); err != nil {
 			return err
 		}
+		slog.Info("json file saved", slog.Int("file_number", pageNumber), slog.Int("tracks", len(tracks)), slog.String("memory", getMemStats()))
+
+		// メモリ解放
+		runtime.GC()
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	pageNumber := 1
-	totalTracks := 0
+	// マーケットを指定した選曲で該当する曲のファイルだけを読めるよう、マーケット別にも書き込む
+	if err := writeMarketPartitions(v, tracksPerFile); err != nil {
+		return err
+	}
 
-	for {
-		// 1ページ分のみ取得（メモリ効率化）
-		tracks, err := database.GetTracks(db, pageNumber, tracksPerFile)
+	v.manifest.CreatedAt = time.Now().UTC()
+	if err := writeManifest(v.dir, v.manifest); err != nil {
+		return err
+	}
+	if err := validateVersion(v); err != nil {
+		return err
+	}
+	return activateVersion(v.manifest.Version)
+}
+
+// writeShard は part 番目のトラックファイルを書き込み、manifest に追加する
+func writeShard(v *catalogVersion, part int, tracks []model.Track) error {
+	// エクスポート用の JSON と、選曲時に読むバイナリ形式の両方を書き込む
+	for _, f := range []struct {
+		path   string
+		format string
+		write  func(string, []model.Track) error
+	}{
+		{v.jsonFilePath(part), FormatJSON, writeTracksToFileStreaming},
+		{v.binaryFilePath(part), FormatBinary, writeTracksToBinaryFile},
+	} {
+		err := retry(3, 1*time.Second, func() error {
+			return f.write(f.path, tracks)
+		})
 		if err != nil {
 			return err
 		}
-		if len(tracks) == 0 {
-			break // データ終了
-		}
-
-		filePath := fmt.Sprintf("%s/%s", baseDirectory, fmt.Sprintf(fileNamePattern, pageNumber))
-
-		err = retry(3, 1*time.Second, func() error {
-			return writeTracksToFileStreaming(filePath, tracks)
-		})
+		info, err := shardFile(v.dir, f.path, part, f.format, len(tracks))
 		if err != nil {
 			return err
 		}
-
-		totalTracks += len(tracks)
-		slog.Info("json file saved", slog.Int("file_number", pageNumber), slog.Int("tracks", len(tracks)), slog.String("memory", getMemStats()))
-
-		// メモリ解放
-		tracks = nil
-		runtime.GC()
-
-		pageNumber++
+		v.manifest.Files = append(v.manifest.Files, info)
 	}
 
-	slog.Info("create json completed",
-		slog.Int("files", pageNumber-1),
-		slog.Int("total_tracks", totalTracks),
-		slog.Duration("duration", time.Since(start)),
-		slog.String("memory", getMemStats()))
-
+	v.manifest.Parts = part
+	v.manifest.TotalTracks += len(tracks)
+	for _, t := range tracks {
+		v.manifest.TotalDurationMs += int64(t.DurationMs)
+		market := marketOf(t)
+		if market == "" {
+			continue
+		}
+		p, ok := v.manifest.Markets[market]
+		if !ok {
+			p = &MarketPartition{}
+			v.manifest.Markets[market] = p
+		}
+		p.Tracks++
+		p.DurationMs += int64(t.DurationMs)
+	}
 	return nil
 }
 
@@ -288,13 +336,45 @@ func Create(db *sql.DB) error {
 		return nil
 	}
 
-	err = createJson(db)
+	// 同時に呼ばれた場合は1回だけ作成する
+	buildMutex.Lock()
+	defer buildMutex.Unlock()
+	if exists, err := checkFilesExist(); err == nil && exists {
+		setFilesExistCache(true)
+		return nil
+	}
+
+	store, err := NewCatalogStore(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	// 他のインスタンスが作成したバージョンがあれば、DBから作成せずに取得する
+	if store != nil {
+		manifest, err := syncFromStore(ctx, store)
+		if err != nil {
+			slog.Warn("failed to fetch catalog from store", slog.Any("error", err))
+		} else if manifest != nil {
+			setFilesExistCache(true)
+			return nil
+		}
+	}
+
+	_, err = createJson(db)
 	if err != nil {
 		slog.Error("error creating json", slog.Any("error", err))
 		return err
 	}
 	slog.Info("json created successfully")
 
+	// 作成は成功しているので、共有に失敗してもこのインスタンスでは作成したバージョンを使う
+	if store != nil {
+		if err := publishVersion(ctx, store); err != nil {
+			slog.Warn("failed to publish catalog", slog.Any("error", err))
+		}
+	}
+
 	// 作成成功後、キャッシュをtrueに設定
 	setFilesExistCache(true)
 
@@ -0,0 +1,190 @@
+package json
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// マーケット別のトラックファイルのディレクトリ（バージョンのディレクトリからの相対パス）
+const marketsDirectory = "markets"
+
+// MarketPartition はマーケット（ISRCの国コード）別のトラックファイルの情報
+type MarketPartition struct {
+	Parts      int   `json:"parts"`
+	Tracks     int   `json:"tracks"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// marketOf は曲のISRCの国コードを返す
+// ISRCの形式: CC-XXX-YY-NNNNN（CCが国コード、例: JP, US, GB）
+// 国コードとして読めない場合は空文字を返す（マーケット別のファイルには含めない）
+func marketOf(t model.Track) string {
+	if len(t.Isrc) < 2 {
+		return ""
+	}
+	cc := strings.ToUpper(t.Isrc[:2])
+	for _, r := range cc {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
+	}
+	return cc
+}
+
+// normalizeMarket はマーケットの指定を大文字の2文字の国コードにする。
+// 無効な国コードの場合は空文字（すべてのマーケット）を返す。
+func normalizeMarket(market string) string {
+	if len(market) < 2 {
+		return ""
+	}
+	return strings.ToUpper(market[:2])
+}
+
+// marketBinaryFilePath は market の part 番目のバイナリ形式のトラックファイルのパスを返す
+func (v *catalogVersion) marketBinaryFilePath(market string, part int) string {
+	return filepath.Join(v.dir, marketsDirectory, market, fmt.Sprintf(binaryFileNamePattern, part))
+}
+
+// partitionFiles は market の曲を読むためのファイルと、読んだ曲に適用する絞り込みの条件を返す。
+// マーケット別のファイルがあればそれを読み、マーケット別のファイルがない古いバージョンでは
+// すべての曲のファイルを読んでISRCの国コードで絞り込む。
+func (v *catalogVersion) partitionFiles(market string) ([]trackFile, func(model.Track) bool) {
+	m := v.manifest
+	if market != "" && m.Markets != nil {
+		p, ok := m.Markets[market]
+		if !ok {
+			return nil, nil // このマーケットの曲はない
+		}
+		files := make([]trackFile, p.Parts)
+		for i := range files {
+			files[i] = trackFile{binary: v.marketBinaryFilePath(market, i+1)}
+		}
+		return files, nil
+	}
+
+	files := make([]trackFile, m.Parts)
+	for i := range files {
+		files[i] = trackFile{binary: v.binaryFilePath(i + 1), json: v.jsonFilePath(i + 1)}
+	}
+	if market == "" {
+		return files, nil
+	}
+	return files, func(t model.Track) bool {
+		return marketOf(t) == market
+	}
+}
+
+// writeMarketPartitions はすべての曲のバイナリ形式のファイルから、マーケット別のトラックファイルを書き込む。
+// すべての曲のファイルは1回だけ読み、マーケットごとのバッファが tracksPerFile 件になるたびに書き出す。
+func writeMarketPartitions(v *catalogVersion, tracksPerFile int) error {
+	buffers := make(map[string][]model.Track, len(v.manifest.Markets))
+	for market, partition := range v.manifest.Markets {
+		partition.Parts = 0
+		if err := os.MkdirAll(filepath.Join(v.dir, marketsDirectory, market), os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	flush := func(market string) error {
+		partition := v.manifest.Markets[market]
+		partition.Parts++
+		path := v.marketBinaryFilePath(market, partition.Parts)
+		if err := writeTracksToBinaryFile(path, buffers[market]); err != nil {
+			return err
+		}
+		info, err := shardFile(v.dir, path, partition.Parts, FormatBinary, len(buffers[market]))
+		if err != nil {
+			return err
+		}
+		info.Market = market
+		v.manifest.Files = append(v.manifest.Files, info)
+		buffers[market] = buffers[market][:0]
+		return nil
+	}
+
+	for part := 1; part <= v.manifest.Parts; part++ {
+		var flushErr error
+		err := eachTrackInFile(trackFile{binary: v.binaryFilePath(part)}, 0, 0, func(t model.Track) {
+			market := marketOf(t)
+			if flushErr != nil || v.manifest.Markets[market] == nil {
+				return
+			}
+			buffers[market] = append(buffers[market], t)
+			if len(buffers[market]) == tracksPerFile {
+				flushErr = flush(market)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if flushErr != nil {
+			return flushErr
+		}
+	}
+
+	// 残りはマーケットの順に書き出す（manifest のファイルの順を一定にするため）
+	markets := make([]string, 0, len(buffers))
+	for market, buf := range buffers {
+		if len(buf) > 0 {
+			markets = append(markets, market)
+		}
+	}
+	sort.Strings(markets)
+	for _, market := range markets {
+		if err := flush(market); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Coverage はマーケットごとのカタログの曲数と再生時間（分）を返す。曲数の多い順に並べる。
+// カタログをまだ読み込んでいない場合は ErrCatalogNotReady を返す。
+func Coverage(db *sql.DB) (*model.CatalogCoverageResponse, error) {
+	// カタログは StartRefresh で読み込む。読み込むまではリクエストの中で作成しない
+	if LoadedVersion() == "" {
+		return nil, model.ErrCatalogNotReady
+	}
+
+	v, err := currentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, model.ErrCatalogNotReady
+	}
+
+	m := v.manifest
+	response := &model.CatalogCoverageResponse{
+		Version:      m.Version,
+		TotalTracks:  m.TotalTracks,
+		TotalMinutes: int(m.TotalDurationMs / time.Minute.Milliseconds()),
+		Markets:      make([]model.MarketCoverage, 0, len(m.Markets)),
+	}
+	for market, p := range m.Markets {
+		response.Markets = append(response.Markets, model.MarketCoverage{
+			Market:  market,
+			Tracks:  p.Tracks,
+			Minutes: int(p.DurationMs / time.Minute.Milliseconds()),
+		})
+	}
+	sort.Slice(response.Markets, func(i, j int) bool {
+		a, b := response.Markets[i], response.Markets[j]
+		if a.Tracks != b.Tracks {
+			return a.Tracks > b.Tracks
+		}
+		return a.Market < b.Market
+	})
+	response.UnknownMarketTracks = m.TotalTracks
+	for _, c := range response.Markets {
+		response.UnknownMarketTracks -= c.Tracks
+	}
+	return response, nil
+}
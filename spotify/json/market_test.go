@@ -0,0 +1,191 @@
+package json
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// マーケット別のトラックファイルのテスト
+// =============================================================================
+// writeMarketPartitions と Coverage が以下を満たすことをテストする:
+// 1. マーケットごとに tracksPerFile 件ずつのファイルに分けて書き込む
+// 2. マーケット別のファイルには、そのマーケットの曲だけが漏れなく含まれる
+// 3. Coverage がマーケットごとの曲数と再生時間を曲数の多い順に返す
+// =============================================================================
+
+// marketTestTracks は JP 7曲、US 3曲、国コードのない3曲（ISRCなし、数字で始まるISRC）を返すテスト用ヘルパー
+func marketTestTracks() []model.Track {
+	var tracks []model.Track
+	add := func(isrc string, n int) {
+		for i := 0; i < n; i++ {
+			tracks = append(tracks, model.Track{
+				Uri:        fmt.Sprintf("spotify:track:%s%d", isrc, i),
+				DurationMs: 120000 + len(tracks)*1000,
+				Isrc:       isrc,
+			})
+		}
+	}
+	add("JPABC2400001", 7)
+	add("USABC2400001", 3)
+	add("", 2)
+	add("12ABC2400001", 1)
+	return tracks
+}
+
+// writeMarketTestVersion は tracks を tracksPerFile 件ずつのファイルに書き込み、
+// マーケット別のファイルも書き込んで current にするテスト用ヘルパー
+func writeMarketTestVersion(t *testing.T, version string, tracks []model.Track, tracksPerFile int) *catalogVersion {
+	t.Helper()
+	v := &catalogVersion{
+		dir:      filepath.Join(versionsDirectory, version),
+		manifest: &Manifest{Version: version, Markets: make(map[string]*MarketPartition)},
+	}
+	if err := os.MkdirAll(v.dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for part := 1; (part-1)*tracksPerFile < len(tracks); part++ {
+		end := min(part*tracksPerFile, len(tracks))
+		if err := writeShard(v, part, tracks[(part-1)*tracksPerFile:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeMarketPartitions(v, tracksPerFile); err != nil {
+		t.Fatalf("writeMarketPartitions() unexpected error: %v", err)
+	}
+	if err := writeManifest(v.dir, v.manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateVersion(v); err != nil {
+		t.Fatalf("validateVersion() unexpected error: %v", err)
+	}
+	if err := activateVersion(version); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// markCatalogLoaded は current を読み込んだ状態にし、テストの後に元に戻すテスト用ヘルパー
+func markCatalogLoaded(t *testing.T) {
+	t.Helper()
+	setFilesExistCache(true)
+	t.Cleanup(func() {
+		filesExistCacheMutex.Lock()
+		defer filesExistCacheMutex.Unlock()
+		filesExistCache = false
+		cacheInitialized = false
+	})
+}
+
+// TestWriteMarketPartitions は、マーケットごとに曲を分けて書き込むことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 13曲（JP 7曲、US 3曲、国コードなし3曲）を4曲ずつ4ファイルに書き込む
+//   - 期待結果: JP は2ファイル（4曲 + 3曲）、US は1ファイル、
+//     マーケット別のファイルにはそのマーケットの曲だけが漏れなく含まれる
+func TestWriteMarketPartitions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tracks := marketTestTracks()
+	v := writeMarketTestVersion(t, "v1", tracks, 4)
+
+	tests := []struct {
+		market      string
+		parts       int
+		partsTracks []int
+	}{
+		{"JP", 2, []int{4, 3}},
+		{"US", 1, []int{3}},
+	}
+	if len(v.manifest.Markets) != len(tests) {
+		t.Errorf("Expected %d markets, got %v", len(tests), v.manifest.Markets)
+	}
+	for _, tt := range tests {
+		t.Run(tt.market, func(t *testing.T) {
+			p := v.manifest.Markets[tt.market]
+			if p == nil || p.Parts != tt.parts {
+				t.Fatalf("Expected %d parts, got %+v", tt.parts, p)
+			}
+
+			var want []string
+			for _, track := range tracks {
+				if marketOf(track) == tt.market {
+					want = append(want, track.Uri)
+				}
+			}
+			files, filter := v.partitionFiles(tt.market)
+			if filter != nil {
+				t.Error("Expected no filter for a partitioned market")
+			}
+			var got []string
+			for i, f := range files {
+				n := 0
+				if err := eachTrackInFile(f, 0, 0, func(track model.Track) {
+					if marketOf(track) != tt.market {
+						t.Errorf("Track %s from another market in %s", track.Uri, tt.market)
+					}
+					got = append(got, track.Uri)
+					n++
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if n != tt.partsTracks[i] {
+					t.Errorf("Part %d: expected %d tracks, got %d", i+1, tt.partsTracks[i], n)
+				}
+			}
+			sort.Strings(want)
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	if files, _ := v.partitionFiles("GB"); len(files) != 0 {
+		t.Errorf("Expected no files for a market without tracks, got %v", files)
+	}
+}
+
+// TestCoverage は、マーケットごとの曲数と再生時間を返すことをテストする。
+//
+// テストシナリオ:
+//   - 入力: 13曲（JP 7曲、US 3曲、国コードなし3曲）
+//   - 期待結果: JP, US の順、曲数と再生時間（分）が一致、国コードなしは3曲
+func TestCoverage(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tracks := marketTestTracks()
+	writeMarketTestVersion(t, "v1", tracks, 4)
+	markCatalogLoaded(t)
+
+	var totalMs int64
+	durations := make(map[string]int64)
+	for _, track := range tracks {
+		totalMs += int64(track.DurationMs)
+		durations[marketOf(track)] += int64(track.DurationMs)
+	}
+
+	response, err := Coverage(nil)
+	if err != nil {
+		t.Fatalf("Coverage() unexpected error: %v", err)
+	}
+	if response.Version != "v1" || response.TotalTracks != len(tracks) {
+		t.Errorf("Expected v1 with %d tracks, got %s with %d", len(tracks), response.Version, response.TotalTracks)
+	}
+	if response.TotalMinutes != int(totalMs/60000) {
+		t.Errorf("Expected %d minutes, got %d", totalMs/60000, response.TotalMinutes)
+	}
+	want := []model.MarketCoverage{
+		{Market: "JP", Tracks: 7, Minutes: int(durations["JP"] / 60000)},
+		{Market: "US", Tracks: 3, Minutes: int(durations["US"] / 60000)},
+	}
+	if fmt.Sprint(response.Markets) != fmt.Sprint(want) {
+		t.Errorf("Expected markets %v, got %v", want, response.Markets)
+	}
+	if response.UnknownMarketTracks != 3 {
+		t.Errorf("Expected 3 tracks without market, got %d", response.UnknownMarketTracks)
+	}
+}
@@ -0,0 +1,48 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+
+	"github.com/pp-develop/music-timer-api/database"
+)
+
+// PostgresStore はDBの catalog_objects テーブルにカタログを保存する CatalogStore（PostgreSQL / CockroachDB）
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore は db の catalog_objects テーブルに保存する PostgresStore を返す
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Put(ctx context.Context, key string, body io.ReadSeeker) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return database.SaveCatalogObject(ctx, s.db, key, data)
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, err := database.GetCatalogObject(ctx, s.db, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	return database.DeleteCatalogObject(ctx, s.db, key)
+}
+
+func (s *PostgresStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return database.GetCatalogObjectKeys(ctx, s.db, prefix)
+}
@@ -1,6 +1,8 @@
 package json
 
 import (
+	"context"
+	"database/sql"
 	"log/slog"
 	"time"
 
@@ -10,31 +12,43 @@ import (
 )
 
 func ReCreate(c *gin.Context) error {
-	start := time.Now()
-	slog.Info("recreate started", slog.String("mem_stats", getMemStats()))
-
 	db, ok := utils.GetDB(c)
 	if !ok {
 		return model.ErrFailedGetDB
 	}
 
-	// キャッシュをクリア
-	ClearFilesExistCache()
+	_, err := Rebuild(c, db)
+	return err
+}
 
-	// 既存ファイルを削除（ファイル数が減った場合に古いファイルが残るのを防ぐ）
-	if err := deleteAllTrackFiles(); err != nil {
-		slog.Error("error deleting old files", slog.Any("error", err))
-		return err
+// Rebuild はDBの曲から新しいバージョンを作成し、検証できたら切り替える。
+// 作成中も読み込み側はそれまでのバージョンを使い続け、失敗した場合もそれまでのバージョンのままにする。
+func Rebuild(ctx context.Context, db *sql.DB) (*Manifest, error) {
+	start := time.Now()
+	slog.Info("recreate started", slog.String("mem_stats", getMemStats()))
+
+	store, err := NewCatalogStore(db)
+	if err != nil {
+		return nil, err
 	}
-	slog.Debug("deleted old files", slog.String("mem_stats", getMemStats()))
 
-	// 新規作成
-	err := createJson(db)
+	buildMutex.Lock()
+	defer buildMutex.Unlock()
+	manifest, err := createJson(db)
 	if err != nil {
 		slog.Error("error creating JSON", slog.Any("error", err))
-		return err
+		return nil, err
+	}
+	setFilesExistCache(true)
+
+	// 他のインスタンスが次に取得するときに、このバージョンを使うようにする
+	if store != nil {
+		if err := publishVersion(ctx, store); err != nil {
+			slog.Error("error publishing catalog", slog.Any("error", err))
+			return nil, err
+		}
 	}
 
-	slog.Info("recreate complete", slog.Duration("duration", time.Since(start)), slog.String("mem_stats", getMemStats()))
-	return nil
+	slog.Info("recreate complete", slog.String("version", manifest.Version), slog.Duration("duration", time.Since(start)), slog.String("mem_stats", getMemStats()))
+	return manifest, nil
 }
@@ -0,0 +1,148 @@
+package json
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/pkg/common/cron"
+)
+
+// カタログの定期的な作成し直し。
+//
+// 起動時にバックグラウンドでDBに接続してカタログを読み込み（CatalogStore から取得、なければDBから作成）、
+// その後はスケジュールに従ってバックグラウンドで作成し直す。
+// リクエストの処理中にカタログを作成しないようにし、作成し直しに失敗した場合はそれまでのバージョンを使い続ける。
+
+// DefaultRefreshSchedule はカタログを作成し直す既定のスケジュール（UTC）。
+// spotify_tracks の Row-Level TTL ジョブ（毎日 03:00 UTC）で古い曲が削除された後に作成し直す。
+const DefaultRefreshSchedule = "30 3 * * *"
+
+// 起動時のDBへの接続やカタログの読み込みに失敗した場合に再試行するまでの時間。
+// 失敗するたびに2倍にし、maxLoadRetryInterval で打ち止めにする。
+const (
+	minLoadRetryInterval = 5 * time.Second
+	maxLoadRetryInterval = 5 * time.Minute
+)
+
+// スケジュールの時刻から作成し直すまでに待つ時間（ランダム）の上限。
+// 同じスケジュールの複数のインスタンスが同時に作成し直さないよう、インスタンスごとにずらす。
+// 先に作成したインスタンスが CatalogStore に保存していれば、後のインスタンスは作成せずに取得する。
+const maxRefreshJitter = 10 * time.Minute
+
+// RefreshSchedule は環境変数 CATALOG_REFRESH_CRON のスケジュールを返す。
+// 未設定の場合は DefaultRefreshSchedule、"off" の場合は nil（起動時の読み込みのみ行う）を返す。
+func RefreshSchedule() (*cron.Schedule, error) {
+	spec := os.Getenv("CATALOG_REFRESH_CRON")
+	switch spec {
+	case "":
+		spec = DefaultRefreshSchedule
+	case "off":
+		return nil, nil
+	}
+	return cron.Parse(spec)
+}
+
+// StartRefresh はカタログの読み込みと定期的な作成し直しをバックグラウンドで開始する。
+// DBには connect で接続する（起動時にDBに接続できなくても、接続できるまで再試行する）。
+// ctx がキャンセルされると停止する。
+func StartRefresh(ctx context.Context, connect func() (*sql.DB, error), schedule *cron.Schedule) {
+	go func() {
+		db, ok := load(ctx, connect)
+		if !ok || schedule == nil {
+			return
+		}
+
+		for {
+			next := schedule.Next(time.Now().UTC())
+			if next.IsZero() {
+				slog.Warn("catalog refresh schedule has no next run")
+				return
+			}
+			jitter := time.Duration(rand.Int63n(int64(maxRefreshJitter)))
+			slog.Info("catalog refresh scheduled", slog.Time("at", next), slog.Duration("jitter", jitter))
+
+			timer := time.NewTimer(time.Until(next) + jitter)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			if err := refresh(ctx, db, next); err != nil {
+				slog.Error("scheduled catalog refresh failed, keeping current version", slog.Any("error", err))
+			}
+		}
+	}()
+}
+
+// load はDBに接続してカタログを読み込めるまで、間隔を延ばしながら再試行する。
+// 接続したDBを返す。ctx がキャンセルされた場合は false を返す。
+func load(ctx context.Context, connect func() (*sql.DB, error)) (*sql.DB, bool) {
+	retryIn := minLoadRetryInterval
+	for {
+		db, err := connect()
+		if err != nil {
+			slog.Error("failed to connect to database, catalog is not loaded yet", slog.Any("error", err), slog.Duration("retry_in", retryIn))
+		} else if err = Create(db); err != nil {
+			slog.Error("failed to load catalog", slog.Any("error", err), slog.Duration("retry_in", retryIn))
+		} else {
+			slog.Info("catalog loaded", slog.String("version", LoadedVersion()))
+			return db, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(retryIn):
+		}
+		retryIn = min(retryIn*2, maxLoadRetryInterval)
+	}
+}
+
+// refresh は scheduledAt の回の作成し直しを行う。
+// CatalogStore を共有している場合、他のインスタンスが scheduledAt 以降に作成したバージョンがあれば、作成せずに取得する。
+// StartRefresh はスケジュールの時刻から maxRefreshJitter までのランダムな時間を待ってから呼ぶため、
+// 待っている間に他のインスタンスが作成したバージョンがあれば、それを使う。
+func refresh(ctx context.Context, db *sql.DB, scheduledAt time.Time) error {
+	store, err := NewCatalogStore(db)
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		synced, err := syncIfNewer(ctx, store, scheduledAt)
+		if err != nil {
+			slog.Warn("failed to fetch catalog from store", slog.Any("error", err))
+		} else if synced {
+			return nil
+		}
+	}
+
+	_, err = Rebuild(ctx, db)
+	return err
+}
+
+// syncIfNewer は CatalogStore の current が since 以降に作成されたバージョンであれば取得して current にする
+func syncIfNewer(ctx context.Context, store CatalogStore, since time.Time) (bool, error) {
+	buildMutex.Lock()
+	defer buildMutex.Unlock()
+
+	version, err := readPointer(ctx, store, storeCurrentKey)
+	if err != nil || version == "" {
+		return false, err
+	}
+	createdAt, err := time.Parse(versionNameLayout, version)
+	if err != nil || createdAt.Before(since) {
+		return false, nil
+	}
+
+	if _, err := syncFromStore(ctx, store); err != nil {
+		return false, err
+	}
+	setFilesExistCache(true)
+	return true, nil
+}
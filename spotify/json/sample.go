@@ -0,0 +1,264 @@
+package json
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// SampleSize は1回の選曲で候補にするカタログの曲数の上限。
+// メモリ使用量を従来の1ファイル分（5万件）に抑える。
+const SampleSize = 50000
+
+// 抽出に失敗した場合に最初から抽出し直す回数
+const sampleRetries = 3
+
+// SampleTracks はカタログから market の曲を最大 SampleSize 件抽出する（market が空文字の場合はすべての曲）。
+//
+// 各ファイルの曲数から抽出する位置をカタログ全体で一様に選び、その曲だけを読むため、
+// 1回の抽出で読み込む量とメモリはカタログの大きさによらず SampleSize 件分に収まる。
+// マーケットを指定した場合は、そのマーケットの曲だけを書き込んだファイルを読む。
+// 同じシードを指定すると同じ曲が抽出される。
+func SampleTracks(db *sql.DB, seed int64, market string) ([]model.Track, error) {
+	return SampleTracksInRange(db, seed, market, 0, 0)
+}
+
+// SampleTracksInRange は再生時間が [minMs, maxMs] の曲に限って SampleTracks と同じ抽出を行う。
+// minMs, maxMs が0の場合はその側を制限しない。
+// バイナリ形式のファイルのインデックスで、該当する再生時間の曲だけを読む。
+// カタログをまだ読み込んでいない場合は ErrCatalogNotReady を返す。
+func SampleTracksInRange(db *sql.DB, seed int64, market string, minMs, maxMs int) ([]model.Track, error) {
+	start := time.Now()
+
+	// カタログは StartRefresh で読み込む。読み込むまではリクエストの中で作成しない
+	if LoadedVersion() == "" {
+		return nil, model.ErrCatalogNotReady
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < sampleRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(1 * time.Second)
+		}
+
+		// 読み込み中に current が差し替わっても、同じバージョンのファイルだけを読む
+		v, err := currentVersion()
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, model.ErrCatalogNotReady
+		}
+
+		sample, matched, err := sampleFiles(v, seed, normalizeMarket(market), minMs, maxMs)
+		if err != nil {
+			// 古いバージョンの削除と重なった可能性があるため、最新のバージョンで最初から抽出し直す
+			lastErr = err
+			slog.Warn("error sampling tracks", slog.Int("attempt", attempt+1), slog.Int("retries", sampleRetries), slog.Any("error", err))
+			continue
+		}
+
+		slog.Debug("sampled tracks from all files",
+			slog.String("version", v.manifest.Version),
+			slog.String("market", market),
+			slog.Int("matched", matched),
+			slog.Int("sampled", len(sample)),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("memory", getMemStats()))
+		return sample, nil
+	}
+	return nil, fmt.Errorf("failed to sample tracks after %d attempts: %w", sampleRetries, lastErr)
+}
+
+// sampleFiles はバージョン v の market の曲から抽出した曲と、該当した曲数を返す。
+// バイナリ形式のファイルがない、またはマーケット別のファイルがない古いバージョンでは、
+// すべての曲を読んでリザーバーサンプリングする。
+func sampleFiles(v *catalogVersion, seed int64, market string, minMs, maxMs int) ([]model.Track, int, error) {
+	files, filter := v.partitionFiles(market)
+	if filter == nil {
+		sample, matched, err := sampleBinaryFiles(files, seed, minMs, maxMs)
+		if !errors.Is(err, os.ErrNotExist) {
+			return sample, matched, err
+		}
+	}
+	return reservoirSampleFiles(files, filter, seed, minMs, maxMs)
+}
+
+// sampleBinaryFiles はバイナリ形式のファイルから曲を抽出する。
+// 各ファイルのヘッダとインデックスだけを読んで該当する曲数を求め、
+// 全体の該当する曲から SampleSize 件の位置を一様に選んで、ファイルごとにその位置の曲だけを読む。
+func sampleBinaryFiles(files []trackFile, seed int64, minMs, maxMs int) ([]model.Track, int, error) {
+	type recordRange struct{ first, last int }
+	ranges := make([]recordRange, len(files))
+	matched := 0
+	for i, f := range files {
+		bf, err := openBinaryTrackFile(f.binary)
+		if err != nil {
+			return nil, 0, err
+		}
+		first, last, err := bf.exactRange(minMs, maxMs)
+		bf.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+		ranges[i] = recordRange{first, last}
+		matched += last - first
+	}
+
+	positions := samplePositions(matched, SampleSize, rand.New(rand.NewSource(seed)))
+	sample := make([]model.Track, 0, len(positions))
+	add := func(t model.Track) { sample = append(sample, t) }
+	offset, p := 0, 0
+	for i, f := range files {
+		n := ranges[i].last - ranges[i].first
+		var selected []int
+		for ; p < len(positions) && positions[p] < offset+n; p++ {
+			selected = append(selected, ranges[i].first+positions[p]-offset)
+		}
+		offset += n
+		if len(selected) == 0 {
+			continue
+		}
+
+		bf, err := openBinaryTrackFile(f.binary)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(selected) == n {
+			// 該当する曲をすべて使う場合はまとめて読む
+			err = bf.each(minMs, maxMs, add)
+		} else {
+			err = bf.eachAt(selected, add)
+		}
+		bf.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return sample, matched, nil
+}
+
+// samplePositions は [0, n) から k 個の位置を重複なく一様に選び、昇順に返す（Floyd のアルゴリズム）。
+// n が k 以下の場合はすべての位置を返す。
+func samplePositions(n, k int, r *rand.Rand) []int {
+	if n <= k {
+		positions := make([]int, n)
+		for i := range positions {
+			positions[i] = i
+		}
+		return positions
+	}
+
+	chosen := make(map[int]bool, k)
+	positions := make([]int, 0, k)
+	for j := n - k; j < n; j++ {
+		i := r.Intn(j + 1)
+		if chosen[i] {
+			i = j
+		}
+		chosen[i] = true
+		positions = append(positions, i)
+	}
+	sort.Ints(positions)
+	return positions
+}
+
+// reservoirSampleFiles はファイルを順にすべて読み、リザーバーサンプリングで曲を抽出する。
+// filter が指定された場合は、一致した曲だけを抽出の対象にする。
+func reservoirSampleFiles(files []trackFile, filter func(model.Track) bool, seed int64, minMs, maxMs int) ([]model.Track, int, error) {
+	r := rand.New(rand.NewSource(seed))
+	sample := make([]model.Track, 0, SampleSize)
+	matched := 0
+	for _, f := range files {
+		err := eachTrackInFile(f, minMs, maxMs, func(t model.Track) {
+			if filter != nil && !filter(t) {
+				return
+			}
+			matched++
+			if len(sample) < SampleSize {
+				sample = append(sample, t)
+				return
+			}
+			// 一致した曲のうち SampleSize 件が等しい確率で残るように入れ替える
+			if i := r.Intn(matched); i < SampleSize {
+				sample[i] = t
+			}
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return sample, matched, nil
+}
+
+// trackFile は読み込むトラックファイル。json はバイナリ形式のファイルがない場合に読む JSON（空文字の場合は読まない）。
+type trackFile struct {
+	binary string
+	json   string
+}
+
+// eachTrackInFile はトラックファイルの、再生時間が [minMs, maxMs] の曲を fn に渡す。
+// バイナリ形式のファイルがあればそれを読み、なければ JSON を読む。
+func eachTrackInFile(f trackFile, minMs, maxMs int, fn func(model.Track)) error {
+	bf, err := openBinaryTrackFile(f.binary)
+	if err == nil {
+		defer bf.Close()
+		return bf.each(minMs, maxMs, fn)
+	}
+	if !os.IsNotExist(err) || f.json == "" {
+		return err
+	}
+
+	return streamTracks(f.json, func(t model.Track) {
+		if (minMs > 0 && t.DurationMs < minMs) || (maxMs > 0 && t.DurationMs > maxMs) {
+			return
+		}
+		fn(t)
+	})
+}
+
+// streamTracks はトラックファイルの曲を1曲ずつデコードして fn に渡す。
+// ファイル全体をメモリに読み込まない。
+func streamTracks(filePath string, fn func(model.Track)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	// {"tracks":[ まで読み進める
+	for _, want := range []interface{}{json.Delim('{'), "tracks", json.Delim('[')} {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if token != want {
+			return fmt.Errorf("unexpected token %v in %s", token, filePath)
+		}
+	}
+
+	for decoder.More() {
+		var t model.Track
+		if err := decoder.Decode(&t); err != nil {
+			return err
+		}
+		fn(t)
+	}
+
+	// ]} を読んでファイルが最後まで書かれていることを確かめる
+	for i := 0; i < 2; i++ {
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// カタログからの抽出のテスト
+// =============================================================================
+// sampleFiles が以下を満たすことをテストする:
+// 1. 曲数が SampleSize を超える場合、各ファイルの曲数に比例して重複なく SampleSize 件を抽出する
+// 2. 再生時間の範囲を指定した場合は、範囲内の曲だけから抽出する
+// 3. 同じシードなら同じ曲を抽出する
+// =============================================================================
+
+// writeShardsVersion は shards をそれぞれバイナリ形式のファイルに書いたバージョンを返すテスト用ヘルパー
+func writeShardsVersion(t *testing.T, shards ...[]model.Track) *catalogVersion {
+	t.Helper()
+	v := &catalogVersion{dir: t.TempDir(), manifest: &Manifest{Parts: len(shards)}}
+	for i, tracks := range shards {
+		if err := writeTracksToBinaryFile(v.binaryFilePath(i+1), tracks); err != nil {
+			t.Fatal(err)
+		}
+		v.manifest.TotalTracks += len(tracks)
+	}
+	return v
+}
+
+// shardTracks は prefix の付いた n 曲（再生時間は1分〜5分に分散）を生成するテスト用ヘルパー
+func shardTracks(prefix string, n int) []model.Track {
+	tracks := make([]model.Track, n)
+	for i := range tracks {
+		tracks[i] = model.Track{
+			Uri:        fmt.Sprintf("%s%d", prefix, i),
+			DurationMs: 60000 + i%240*1000,
+			Isrc:       "JPABC2400001",
+		}
+	}
+	return tracks
+}
+
+// TestSampleFiles_Proportional は、各ファイルの曲数に比例して抽出することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 40000曲と20000曲のファイル（合計60000曲）
+//   - 期待結果: 該当60000曲、抽出 SampleSize 件、重複なし、
+//     ファイルごとの件数が曲数の比（2:1）に近い、同じシードなら同じ結果
+func TestSampleFiles_Proportional(t *testing.T) {
+	v := writeShardsVersion(t, shardTracks("a", 40000), shardTracks("b", 20000))
+
+	sample, matched, err := sampleFiles(v, 1, "", 0, 0)
+	if err != nil {
+		t.Fatalf("sampleFiles() unexpected error: %v", err)
+	}
+	if matched != 60000 {
+		t.Errorf("Expected 60000 matched, got %d", matched)
+	}
+	if len(sample) != SampleSize {
+		t.Fatalf("Expected %d sampled, got %d", SampleSize, len(sample))
+	}
+
+	seen := make(map[string]bool)
+	fromA := 0
+	for _, track := range sample {
+		if seen[track.Uri] {
+			t.Fatalf("Track %s sampled twice", track.Uri)
+		}
+		seen[track.Uri] = true
+		if track.Uri[0] == 'a' {
+			fromA++
+		}
+	}
+	// 期待値は 50000 × 2/3 ≒ 33333（標準偏差は約50）
+	if fromA < 33000 || fromA > 33700 {
+		t.Errorf("Expected about 33333 tracks from the larger file, got %d", fromA)
+	}
+
+	again, _, err := sampleFiles(v, 1, "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sample {
+		if sample[i].Uri != again[i].Uri {
+			t.Fatalf("Expected the same sample for the same seed, differs at %d", i)
+		}
+	}
+}
+
+// TestSampleFiles_Range は、再生時間の範囲内の曲だけから抽出することをテストする。
+//
+// テストシナリオ:
+//   - 入力: 2ファイル（合計60000曲、再生時間は1分〜5分）
+//   - 要求: 2分〜2分59.999秒
+//   - 期待結果: 該当する曲数と一致し、抽出した曲がすべて範囲内
+func TestSampleFiles_Range(t *testing.T) {
+	shards := [][]model.Track{shardTracks("a", 40000), shardTracks("b", 20000)}
+	v := writeShardsVersion(t, shards...)
+	want := 0
+	for _, tracks := range shards {
+		for _, track := range tracks {
+			if track.DurationMs >= 120000 && track.DurationMs <= 179999 {
+				want++
+			}
+		}
+	}
+
+	sample, matched, err := sampleFiles(v, 1, "", 120000, 179999)
+	if err != nil {
+		t.Fatalf("sampleFiles() unexpected error: %v", err)
+	}
+	if matched != want {
+		t.Errorf("Expected %d matched, got %d", want, matched)
+	}
+	if len(sample) != want {
+		t.Errorf("Expected all %d tracks in range, got %d", want, len(sample))
+	}
+	for _, track := range sample {
+		if track.DurationMs < 120000 || track.DurationMs > 179999 {
+			t.Fatalf("Track %s out of range: %d", track.Uri, track.DurationMs)
+		}
+	}
+}
+
+// TestSampleFiles_JSONFallback は、バイナリ形式のファイルがない古いバージョンでは JSON から抽出することをテストする。
+func TestSampleFiles_JSONFallback(t *testing.T) {
+	v := &catalogVersion{dir: t.TempDir(), manifest: &Manifest{Parts: 1}}
+	data := `{"tracks":[{"uri":"a","duration_ms":180000},{"uri":"b","duration_ms":240000}]}`
+	if err := os.WriteFile(filepath.Join(v.dir, fmt.Sprintf(fileNamePattern, 1)), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sample, matched, err := sampleFiles(v, 1, "", 0, 200000)
+	if err != nil {
+		t.Fatalf("sampleFiles() unexpected error: %v", err)
+	}
+	if matched != 1 || len(sample) != 1 || sample[0].Uri != "a" {
+		t.Errorf("Expected only track a, got %v (matched %d)", sample, matched)
+	}
+}
+
+// TestSamplePositions は、位置が重複なく昇順で範囲内に選ばれることをテストする。
+func TestSamplePositions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	positions := samplePositions(1000, 100, r)
+	if len(positions) != 100 {
+		t.Fatalf("Expected 100 positions, got %d", len(positions))
+	}
+	for i, p := range positions {
+		if p < 0 || p >= 1000 {
+			t.Errorf("Position %d out of range", p)
+		}
+		if i > 0 && p <= positions[i-1] {
+			t.Errorf("Positions are not strictly increasing at %d", i)
+		}
+	}
+
+	if all := samplePositions(5, 100, r); len(all) != 5 {
+		t.Errorf("Expected all 5 positions, got %v", all)
+	}
+}
+
+// TestSampleTracks_NotReady は、カタログを読み込む前はリクエストの中で作成せずにエラーを返すことをテストする。
+//
+// テストシナリオ:
+//   - カタログを読み込む前（DBなし）に抽出する → ErrCatalogNotReady、バージョンは作成されない
+//   - 読み込んだ後に抽出する → 成功
+func TestSampleTracks_NotReady(t *testing.T) {
+	t.Chdir(t.TempDir())
+	markCatalogLoaded(t)
+	setFilesExistCache(false)
+
+	if _, err := SampleTracks(nil, 1, ""); !errors.Is(err, model.ErrCatalogNotReady) {
+		t.Errorf("Expected ErrCatalogNotReady, got %v", err)
+	}
+	if _, err := Coverage(nil); !errors.Is(err, model.ErrCatalogNotReady) {
+		t.Errorf("Expected ErrCatalogNotReady from Coverage, got %v", err)
+	}
+	if _, err := os.Stat(versionsDirectory); !os.IsNotExist(err) {
+		t.Errorf("Expected no catalog version to be created, got %v", err)
+	}
+
+	writeTestVersion(t, "v1", testTracks("a", 3))
+	setFilesExistCache(true)
+	sample, err := SampleTracks(nil, 1, "")
+	if err != nil || len(sample) != 3 {
+		t.Errorf("Expected 3 tracks after load, got %d (%v)", len(sample), err)
+	}
+}
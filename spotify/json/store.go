@@ -0,0 +1,289 @@
+package json
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// カタログの共有。
+//
+// CatalogStore を設定すると、作成したバージョンを CatalogStore に保存し、
+// 他のインスタンスはDBから作成し直さずに CatalogStore から取得する。
+// ローカルの versions/ は CatalogStore から取得したバージョンのキャッシュになる。
+//
+// CatalogStore のキー:
+//
+//	versions/<バージョン>/<ファイル名>  manifest.json とトラックファイル
+//	current, previous                    バージョン名だけを書いたポインタ
+const (
+	storeCurrentKey  = "current"
+	storePreviousKey = "previous"
+)
+
+// ErrObjectNotFound は CatalogStore にキーが存在しない
+var ErrObjectNotFound = errors.New("catalog object not found")
+
+// CatalogStore はカタログのバージョンを複数のインスタンスで共有するための保存先
+type CatalogStore interface {
+	// Put は key に body の内容を保存する（既にあれば上書きする）
+	Put(ctx context.Context, key string, body io.ReadSeeker) error
+	// Get は key の内容を返す。存在しない場合は ErrObjectNotFound を返す
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete は key を削除する。存在しない場合は何もしない
+	Delete(ctx context.Context, key string) error
+	// List は prefix で始まるキーを返す
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// NewCatalogStore は環境変数 CATALOG_STORE に応じた CatalogStore を返す。
+// 未設定の場合は nil（共有せず、各インスタンスがローカルに作成する）を返す。
+//
+//	fs       CATALOG_STORE_DIR のディレクトリ（共有ボリュームなど）
+//	s3       S3互換のバケット（CATALOG_S3_* を参照）
+//	postgres db の catalog_objects テーブル
+func NewCatalogStore(db *sql.DB) (CatalogStore, error) {
+	switch kind := os.Getenv("CATALOG_STORE"); kind {
+	case "":
+		return nil, nil
+	case "fs":
+		dir := os.Getenv("CATALOG_STORE_DIR")
+		if dir == "" {
+			return nil, errors.New("CATALOG_STORE_DIR is not set")
+		}
+		return NewFSStore(dir), nil
+	case "s3":
+		return NewS3StoreFromEnv()
+	case "postgres":
+		if db == nil {
+			return nil, model.ErrFailedGetDB
+		}
+		return NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown CATALOG_STORE %q", kind)
+	}
+}
+
+// storeVersionKey はバージョンのファイルのキーを返す
+func storeVersionKey(version, name string) string {
+	return "versions/" + version + "/" + filepath.ToSlash(name)
+}
+
+// readPointer は current / previous が指すバージョン名を返す。存在しない場合は空文字を返す。
+func readPointer(ctx context.Context, store CatalogStore, key string) (string, error) {
+	body, err := store.Get(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// putFile はローカルのファイルを CatalogStore に保存する
+func putFile(ctx context.Context, store CatalogStore, key, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return store.Put(ctx, key, file)
+}
+
+// publishVersion はローカルの current と previous のバージョンを CatalogStore に保存し、ポインタを差し替える。
+// ポインタはファイルをすべて保存してから書くため、他のインスタンスが途中までのバージョンを取得することはない。
+func publishVersion(ctx context.Context, store CatalogStore) error {
+	pointers := map[string]string{storeCurrentKey: "", storePreviousKey: ""}
+	for key, link := range map[string]string{storeCurrentKey: currentLink, storePreviousKey: previousLink} {
+		v, err := resolveVersion(link)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			continue
+		}
+		if err := uploadVersion(ctx, store, v); err != nil {
+			return err
+		}
+		pointers[key] = v.manifest.Version
+	}
+	if pointers[storeCurrentKey] == "" {
+		return errors.New("no catalog version to publish")
+	}
+
+	for _, key := range []string{storePreviousKey, storeCurrentKey} {
+		var err error
+		if pointers[key] == "" {
+			// 直前のバージョンがない場合は、削除するバージョンを previous が指したままにしない
+			err = store.Delete(ctx, key)
+		} else {
+			err = store.Put(ctx, key, strings.NewReader(pointers[key]))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	pruneStoreVersions(ctx, store, pointers[storeCurrentKey], pointers[storePreviousKey])
+	slog.Info("catalog published", slog.String("current", pointers[storeCurrentKey]), slog.String("previous", pointers[storePreviousKey]))
+	return nil
+}
+
+// uploadVersion はバージョンのファイルと manifest を CatalogStore に保存する。
+// manifest が既に保存されているバージョンは保存済みとみなす。
+func uploadVersion(ctx context.Context, store CatalogStore, v *catalogVersion) error {
+	manifestKey := storeVersionKey(v.manifest.Version, manifestFileName)
+	if body, err := store.Get(ctx, manifestKey); err == nil {
+		body.Close()
+		return nil
+	} else if !errors.Is(err, ErrObjectNotFound) {
+		return err
+	}
+
+	for _, f := range v.manifest.Files {
+		if err := putFile(ctx, store, storeVersionKey(v.manifest.Version, f.Name), filepath.Join(v.dir, f.Name)); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", f.Name, err)
+		}
+	}
+	// manifest は最後に保存する（manifest があればファイルはすべて保存されている）
+	return putFile(ctx, store, manifestKey, filepath.Join(v.dir, manifestFileName))
+}
+
+// pruneStoreVersions は CatalogStore から current と previous 以外のバージョンを削除する。
+// 削除に失敗しても取得には影響しないため、ログに残すだけにする。
+func pruneStoreVersions(ctx context.Context, store CatalogStore, keep ...string) {
+	keys, err := store.List(ctx, "versions/")
+	if err != nil {
+		slog.Warn("failed to list stored catalog versions", slog.Any("error", err))
+		return
+	}
+	for _, key := range keys {
+		version := strings.SplitN(strings.TrimPrefix(key, "versions/"), "/", 2)[0]
+		if slices.Contains(keep, version) {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			slog.Warn("failed to delete stored catalog file", slog.String("key", key), slog.Any("error", err))
+		}
+	}
+}
+
+// fetchVersion は CatalogStore のバージョンをローカルの versions/ に取得して検証する。
+// 既にローカルにあり検証できる場合は取得しない。
+func fetchVersion(ctx context.Context, store CatalogStore, version string) (*catalogVersion, error) {
+	dir := filepath.Join(versionsDirectory, version)
+	if manifest, err := readManifest(dir); err == nil {
+		v := &catalogVersion{dir: dir, manifest: manifest}
+		if validateVersion(v) == nil {
+			return v, nil
+		}
+	}
+
+	// 取得中のディレクトリを current にしないよう、一時ディレクトリに取得してから rename する
+	tmp := dir + ".download"
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+	v, err := downloadVersion(ctx, store, version, tmp)
+	if err == nil {
+		err = validateVersion(v)
+	}
+	if err == nil {
+		if err = os.RemoveAll(dir); err == nil {
+			err = os.Rename(tmp, dir)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, fmt.Errorf("failed to fetch catalog version %s: %w", version, err)
+	}
+	v.dir = dir
+	return v, nil
+}
+
+// downloadVersion はバージョンの manifest とファイルを dir に書き込む
+func downloadVersion(ctx context.Context, store CatalogStore, version, dir string) (*catalogVersion, error) {
+	if err := downloadFile(ctx, store, storeVersionKey(version, manifestFileName), filepath.Join(dir, manifestFileName)); err != nil {
+		return nil, err
+	}
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range manifest.Files {
+		if err := downloadFile(ctx, store, storeVersionKey(version, f.Name), filepath.Join(dir, f.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return &catalogVersion{dir: dir, manifest: manifest}, nil
+}
+
+// downloadFile は key の内容をローカルの path に書き込む
+func downloadFile(ctx context.Context, store CatalogStore, key, path string) error {
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncFromStore は CatalogStore の current のバージョンを取得してローカルの current にする。
+// CatalogStore にまだバージョンがない場合は nil を返す。
+func syncFromStore(ctx context.Context, store CatalogStore) (*Manifest, error) {
+	version, err := readPointer(ctx, store, storeCurrentKey)
+	if err != nil || version == "" {
+		return nil, err
+	}
+
+	v, err := fetchVersion(ctx, store, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := activateVersion(version); err != nil {
+		return nil, err
+	}
+	slog.Info("catalog fetched from store", slog.String("version", version), slog.Int("total_tracks", v.manifest.TotalTracks))
+	return v.manifest, nil
+}
+
+// fetchPreviousFromStore は CatalogStore の previous のバージョンを取得する。
+// previous がない場合、または current と同じバージョンの場合は nil を返す。
+func fetchPreviousFromStore(ctx context.Context, store CatalogStore) (*catalogVersion, error) {
+	version, err := readPointer(ctx, store, storePreviousKey)
+	if err != nil || version == "" {
+		return nil, err
+	}
+	if current, err := currentVersion(); err == nil && current != nil && current.manifest.Version == version {
+		return nil, nil
+	}
+	return fetchVersion(ctx, store, version)
+}
@@ -0,0 +1,332 @@
+package json
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// カタログのバージョン管理。
+//
+// 作成のたびに versions/<バージョン>/ に新しいトラックファイル一式と manifest.json を書き込み、
+// manifest のとおりに書き込めていることを確かめてから current シンボリックリンクを差し替える。
+// 差し替えは rename で行うため、読み込み側は常に完全なバージョンのどちらか一方を見る。
+// 作成に失敗した場合は current を変えないので、読み込み側はそれまでのバージョンを使い続ける。
+// 直前のバージョンは previous として残し、Rollback で戻せるようにする。
+const (
+	versionsDirectory = baseDirectory + "/versions"
+	currentLink       = baseDirectory + "/current"
+	previousLink      = baseDirectory + "/previous"
+	manifestFileName  = "manifest.json"
+)
+
+// トラックファイルの形式
+const (
+	FormatJSON   = "json"
+	FormatBinary = "binary"
+)
+
+// Manifest はカタログの1つのバージョンに含まれるトラックファイルの一覧
+type Manifest struct {
+	Version     string    `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Parts       int       `json:"parts"`
+	TotalTracks int       `json:"total_tracks"`
+	// TotalDurationMs はすべての曲の再生時間の合計
+	TotalDurationMs int64 `json:"total_duration_ms"`
+	// Markets はマーケット（ISRCの国コード）別のトラックファイルの情報
+	Markets map[string]*MarketPartition `json:"markets,omitempty"`
+	Files   []ShardFile                 `json:"files"`
+}
+
+// ShardFile はトラックファイル1つの情報
+type ShardFile struct {
+	Name   string `json:"name"`
+	Part   int    `json:"part"`
+	Format string `json:"format"`
+	// Market はマーケット別のトラックファイルの国コード（すべての曲のファイルは空文字）
+	Market string `json:"market,omitempty"`
+	Tracks int    `json:"tracks"`
+	Bytes  int64  `json:"bytes"`
+	Sha256 string `json:"sha256"`
+}
+
+// catalogVersion はカタログの1つのバージョンのディレクトリと manifest
+type catalogVersion struct {
+	dir      string
+	manifest *Manifest
+}
+
+// jsonFilePath は part 番目の JSON 形式のトラックファイルのパスを返す
+func (v *catalogVersion) jsonFilePath(part int) string {
+	return filepath.Join(v.dir, fmt.Sprintf(fileNamePattern, part))
+}
+
+// binaryFilePath は part 番目のバイナリ形式のトラックファイルのパスを返す
+func (v *catalogVersion) binaryFilePath(part int) string {
+	return filepath.Join(v.dir, fmt.Sprintf(binaryFileNamePattern, part))
+}
+
+// バージョンの名前（作成時刻）の形式
+const versionNameLayout = "20060102T150405.000000000Z"
+
+// newVersionName は新しいバージョンの名前（作成時刻）を返す
+func newVersionName() string {
+	return time.Now().UTC().Format(versionNameLayout)
+}
+
+// versionLinkTarget はシンボリックリンクに書くバージョンのディレクトリ（baseDirectory からの相対パス）
+func versionLinkTarget(version string) string {
+	return filepath.Join("versions", version)
+}
+
+// currentVersion は current が指すバージョンを返す。まだ作成されていなければ nil を返す。
+func currentVersion() (*catalogVersion, error) {
+	return resolveVersion(currentLink)
+}
+
+// resolveVersion はシンボリックリンクが指すバージョンを読み込む。リンクがなければ nil を返す。
+func resolveVersion(link string) (*catalogVersion, error) {
+	target, err := os.Readlink(link)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(baseDirectory, target)
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &catalogVersion{dir: dir, manifest: manifest}, nil
+}
+
+// readManifest はバージョンのディレクトリから manifest.json を読み込む
+func readManifest(dir string) (*Manifest, error) {
+	file, err := os.Open(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest in %s: %w", dir, err)
+	}
+	return &manifest, nil
+}
+
+// writeManifest はバージョンのディレクトリに manifest.json を書き込む
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestFileName), data, 0o644)
+}
+
+// shardFile は書き込んだトラックファイルの大きさとチェックサムを求めて ShardFile を返す。
+// Name はバージョンのディレクトリ dir からの相対パスにする。
+func shardFile(dir, path string, part int, format string, tracks int) (ShardFile, error) {
+	sum, size, err := fileChecksum(path)
+	if err != nil {
+		return ShardFile{}, err
+	}
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		return ShardFile{}, err
+	}
+	return ShardFile{
+		Name:   name,
+		Part:   part,
+		Format: format,
+		Tracks: tracks,
+		Bytes:  size,
+		Sha256: sum,
+	}, nil
+}
+
+// fileChecksum はファイルの SHA-256 と大きさを返す
+func fileChecksum(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// validateVersion は manifest のとおりにトラックファイルが書き込まれていることを確かめる
+func validateVersion(v *catalogVersion) error {
+	m := v.manifest
+	if m.TotalTracks == 0 || m.Parts == 0 {
+		return fmt.Errorf("catalog version %s is empty", m.Version)
+	}
+
+	binaryTracks := 0
+	marketTracks := make(map[string]int, len(m.Markets))
+	for _, f := range m.Files {
+		path := filepath.Join(v.dir, f.Name)
+		sum, size, err := fileChecksum(path)
+		if err != nil {
+			return err
+		}
+		if size != f.Bytes || sum != f.Sha256 {
+			return fmt.Errorf("checksum mismatch for %s", path)
+		}
+
+		if f.Format != FormatBinary {
+			continue
+		}
+		bf, err := openBinaryTrackFile(path)
+		if err != nil {
+			return err
+		}
+		count := bf.recordCount
+		bf.Close()
+		if count != f.Tracks {
+			return fmt.Errorf("%s has %d tracks, manifest says %d", path, count, f.Tracks)
+		}
+		if f.Market != "" {
+			marketTracks[f.Market] += count
+		} else {
+			binaryTracks += count
+		}
+	}
+	for market, p := range m.Markets {
+		if marketTracks[market] != p.Tracks {
+			return fmt.Errorf("market %s has %d tracks, manifest says %d", market, marketTracks[market], p.Tracks)
+		}
+	}
+
+	if binaryTracks != m.TotalTracks {
+		return fmt.Errorf("catalog version %s has %d tracks, manifest says %d", m.Version, binaryTracks, m.TotalTracks)
+	}
+	return nil
+}
+
+// activateVersion は current を version に差し替え、それまでの current を previous にする。
+// 差し替えた後、current と previous 以外のバージョンを削除する。
+func activateVersion(version string) error {
+	old, err := os.Readlink(currentLink)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	target := versionLinkTarget(version)
+	if err := swapLink(currentLink, target); err != nil {
+		return err
+	}
+	if old != "" && old != target {
+		if err := swapLink(previousLink, old); err != nil {
+			return err
+		}
+	}
+
+	pruneVersions()
+	return nil
+}
+
+// swapLink はシンボリックリンク link を target に向ける。
+// 一時的なリンクを作ってから rename するため、link が存在しない瞬間はない。
+func swapLink(link, target string) error {
+	tmp := link + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, link)
+}
+
+// pruneVersions は current と previous 以外のバージョンを削除する。
+// 削除に失敗しても読み込みには影響しないため、ログに残すだけにする。
+func pruneVersions() {
+	keep := make(map[string]bool)
+	for _, link := range []string{currentLink, previousLink} {
+		if target, err := os.Readlink(link); err == nil {
+			keep[filepath.Base(target)] = true
+		}
+	}
+
+	entries, err := os.ReadDir(versionsDirectory)
+	if err != nil {
+		slog.Warn("failed to list catalog versions", slog.Any("error", err))
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || keep[e.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(versionsDirectory, e.Name())); err != nil {
+			slog.Warn("failed to delete catalog version", slog.String("version", e.Name()), slog.Any("error", err))
+			continue
+		}
+		slog.Info("deleted catalog version", slog.String("version", e.Name()))
+	}
+}
+
+// Rollback は current を previous のバージョンに戻し、previous には戻す前の current を残す
+// CatalogStore を設定している場合は、ローカルに previous がなければ CatalogStore から取得し、
+// 戻した後に CatalogStore のポインタも差し替える。
+func Rollback(db *sql.DB) (*Manifest, error) {
+	store, err := NewCatalogStore(db)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	buildMutex.Lock()
+	defer buildMutex.Unlock()
+
+	prev, err := resolveVersion(previousLink)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil && store != nil {
+		if prev, err = fetchPreviousFromStore(ctx, store); err != nil {
+			return nil, err
+		}
+	}
+	if prev == nil {
+		return nil, model.WithDetails(model.ErrNotFoundTracks, map[string]interface{}{
+			"reason": "no_previous_catalog_version",
+		})
+	}
+	if err := validateVersion(prev); err != nil {
+		return nil, err
+	}
+
+	if err := activateVersion(prev.manifest.Version); err != nil {
+		return nil, err
+	}
+	setFilesExistCache(true)
+
+	if store != nil {
+		if err := publishVersion(ctx, store); err != nil {
+			return nil, err
+		}
+	}
+
+	slog.Info("catalog rolled back", slog.String("version", prev.manifest.Version))
+	return prev.manifest, nil
+}
@@ -0,0 +1,203 @@
+package json
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pp-develop/music-timer-api/model"
+)
+
+// =============================================================================
+// カタログのバージョン管理のテスト
+// =============================================================================
+// バージョンの検証・切り替え・削除・Rollback が以下を満たすことをテストする:
+// 1. manifest とトラックファイルが一致しないバージョンは使わない
+// 2. current を差し替えると、それまでの current が previous になる
+// 3. current と previous 以外のバージョンは削除する
+// 4. Rollback で previous に戻し、戻す前の current を previous に残す
+// =============================================================================
+
+// linkTarget はシンボリックリンクが指すバージョン名を返すテスト用ヘルパー
+func linkTarget(t *testing.T, link string) string {
+	t.Helper()
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("Readlink(%s): %v", link, err)
+	}
+	return filepath.Base(target)
+}
+
+// TestValidateVersion は、manifest とトラックファイルが一致しない場合にエラーを返すことをテストする。
+//
+// テストシナリオ:
+//   - 書き込んだままのバージョン → 成功
+//   - 大きさを変えずに1バイト書き換えたトラックファイル → チェックサムの不一致でエラー
+//   - manifest の曲数が実際と異なる → エラー
+func TestValidateVersion(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestVersion(t, "v1", testTracks("a", 3))
+
+	v, err := currentVersion()
+	if err != nil || v == nil {
+		t.Fatalf("Expected current version, got %v (%v)", v, err)
+	}
+	if err := validateVersion(v); err != nil {
+		t.Fatalf("validateVersion() unexpected error: %v", err)
+	}
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		path := v.binaryFilePath(1)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := append([]byte(nil), data...)
+		t.Cleanup(func() { os.WriteFile(path, original, 0o644) })
+
+		data[len(data)-1] ^= 0xff
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		err = validateVersion(v)
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("Expected checksum mismatch, got %v", err)
+		}
+	})
+
+	t.Run("track count mismatch", func(t *testing.T) {
+		broken := *v.manifest
+		broken.Files = append([]ShardFile(nil), v.manifest.Files...)
+		broken.Files[0].Tracks = 4
+		broken.TotalTracks = 4
+
+		if err := validateVersion(&catalogVersion{dir: v.dir, manifest: &broken}); err == nil {
+			t.Error("Expected error for track count mismatch")
+		}
+	})
+}
+
+// TestSwapLink は、既存のシンボリックリンクを差し替えられることをテストする。
+//
+// テストシナリオ:
+//   - リンクがない状態で swapLink → 作成される
+//   - 既存のリンクに swapLink → 新しい向き先に変わり、一時的なリンクが残らない
+func TestSwapLink(t *testing.T) {
+	t.Chdir(t.TempDir())
+	link := "current"
+
+	if err := swapLink(link, "versions/v1"); err != nil {
+		t.Fatalf("swapLink() unexpected error: %v", err)
+	}
+	if got := linkTarget(t, link); got != "v1" {
+		t.Errorf("Expected v1, got %s", got)
+	}
+
+	if err := swapLink(link, "versions/v2"); err != nil {
+		t.Fatalf("swapLink() unexpected error: %v", err)
+	}
+	if got := linkTarget(t, link); got != "v2" {
+		t.Errorf("Expected v2, got %s", got)
+	}
+	if _, err := os.Lstat(link + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary link to be removed, got %v", err)
+	}
+}
+
+// TestActivateVersion_Prune は、current と previous 以外のバージョンを削除することをテストする。
+//
+// テストシナリオ:
+//   - v1, v2, v3 の順に作成する
+//   - 期待結果: current = v3, previous = v2、v1 のディレクトリは削除される
+func TestActivateVersion_Prune(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestVersion(t, "v1", testTracks("a", 3))
+	writeTestVersion(t, "v2", testTracks("b", 3))
+	writeTestVersion(t, "v3", testTracks("c", 3))
+
+	if got := linkTarget(t, currentLink); got != "v3" {
+		t.Errorf("Expected current v3, got %s", got)
+	}
+	if got := linkTarget(t, previousLink); got != "v2" {
+		t.Errorf("Expected previous v2, got %s", got)
+	}
+
+	entries, err := os.ReadDir(versionsDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "v2" || names[1] != "v3" {
+		t.Errorf("Expected only v2 and v3 to remain, got %v", names)
+	}
+}
+
+// TestRollback は、previous に戻し、戻す前の current を previous に残すことをテストする。
+//
+// テストシナリオ:
+//   - v1, v2 の順に作成して Rollback → current = v1, previous = v2
+//   - もう一度 Rollback → current = v2, previous = v1（元に戻る）
+func TestRollback(t *testing.T) {
+	t.Setenv("CATALOG_STORE", "")
+	t.Chdir(t.TempDir())
+	writeTestVersion(t, "v1", testTracks("a", 3))
+	writeTestVersion(t, "v2", testTracks("b", 5))
+
+	manifest, err := Rollback(nil)
+	if err != nil {
+		t.Fatalf("Rollback() unexpected error: %v", err)
+	}
+	if manifest.Version != "v1" || manifest.TotalTracks != 3 {
+		t.Errorf("Expected v1 with 3 tracks, got %+v", manifest)
+	}
+	if got := linkTarget(t, currentLink); got != "v1" {
+		t.Errorf("Expected current v1, got %s", got)
+	}
+	if got := linkTarget(t, previousLink); got != "v2" {
+		t.Errorf("Expected previous v2, got %s", got)
+	}
+
+	if _, err := Rollback(nil); err != nil {
+		t.Fatalf("second Rollback() unexpected error: %v", err)
+	}
+	if got := linkTarget(t, currentLink); got != "v2" {
+		t.Errorf("Expected current v2, got %s", got)
+	}
+	if got := linkTarget(t, previousLink); got != "v1" {
+		t.Errorf("Expected previous v1, got %s", got)
+	}
+}
+
+// TestRollback_Fails は、戻せない場合に current を変えないことをテストする。
+//
+// テストシナリオ:
+//   - previous がない → ErrNotFoundTracks
+//   - previous のトラックファイルが壊れている → エラー、current は v2 のまま
+func TestRollback_Fails(t *testing.T) {
+	t.Setenv("CATALOG_STORE", "")
+	t.Chdir(t.TempDir())
+	writeTestVersion(t, "v1", testTracks("a", 3))
+
+	if _, err := Rollback(nil); !errors.Is(err, model.ErrNotFoundTracks) {
+		t.Errorf("Expected ErrNotFoundTracks without previous, got %v", err)
+	}
+
+	writeTestVersion(t, "v2", testTracks("b", 5))
+	path := filepath.Join(versionsDirectory, "v1", "tracks_part_1.bin")
+	if err := os.WriteFile(path, []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Rollback(nil); err == nil {
+		t.Error("Expected error for corrupted previous version")
+	}
+	if got := linkTarget(t, currentLink); got != "v2" {
+		t.Errorf("Expected current to stay v2, got %s", got)
+	}
+}
@@ -0,0 +1,91 @@
+package playlist
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/preview"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/spotify/auth"
+	"github.com/pp-develop/music-timer-api/spotify/track"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+// count を省略した場合に返す候補数
+const defaultCandidateCount = 3
+
+type PlaylistCandidatesRequest struct {
+	PreviewPlaylistRequest
+	Count int `json:"count" binding:"omitempty,min=1,max=10"`
+}
+
+// GetPlaylistCandidates は同じ候補プールから曲の組み合わせが異なる候補を複数返す。
+// 候補プールの読み込み（カタログの場合はシャードの読み込み）は1回だけ行う。
+// 各候補はプレビューとして保存し、返したトークンを CommitPlaylist に渡すとその候補の曲でプレイリストが作成される。
+func GetPlaylistCandidates(c *gin.Context) (*model.PlaylistCandidatesResponse, error) {
+	var json PlaylistCandidatesRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		return nil, err
+	}
+
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	if json.Source == commontrack.SegmentSourceArtists && len(json.ArtistIds) == 0 {
+		return nil, model.ErrInvalidRequest
+	}
+	count := json.Count
+	if count == 0 {
+		count = defaultCandidateCount
+	}
+
+	user, err := auth.GetUserWithValidToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	ctx := c.Request.Context()
+	opts, err := prepareOptions(ctx, dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("selecting playlist candidates", slog.String("source", json.Source), slog.Int("duration_ms", specifyMs), slog.Int("count", count), slog.Int64("seed", opts.Seed))
+
+	pool, err := getPool(dbInstance, user, json.Source, json.ArtistIds, json.Market, opts.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Source = "spotify/" + json.Source
+	opts.Origin = json.Source
+	if json.Source == commontrack.SegmentSourceCatalog {
+		opts.GapFill = nil
+	}
+	candidates, err := commontrack.SelectCandidates(ctx, pool, specifyMs, count, opts)
+	if err != nil {
+		slog.Error("failed to select candidates", slog.Any("error", err))
+		return nil, err
+	}
+
+	return preview.SaveCandidates(dbInstance, model.ServiceSpotify, user.Id, specifyMs, candidates)
+}
+
+// getPool は選曲元に応じて CreatePlaylist* と同じ候補プールを取得する
+func getPool(db *sql.DB, user model.User, source string, artistIds []string, market string, seed int64) ([]model.Track, error) {
+	switch source {
+	case commontrack.SegmentSourceFavorites:
+		return track.GetFavoritePool(db, nil, user.Id)
+	case commontrack.SegmentSourceArtists:
+		return track.GetArtistsPool(db, artistIds)
+	default:
+		return track.GetCatalogPool(db, market, seed)
+	}
+}
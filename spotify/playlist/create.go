@@ -4,8 +4,6 @@ import (
 	"log/slog"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pp-develop/music-timer-api/api/spotify"
-	"github.com/pp-develop/music-timer-api/database"
 	"github.com/pp-develop/music-timer-api/model"
 	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
 	"github.com/pp-develop/music-timer-api/spotify/auth"
@@ -14,64 +12,61 @@ import (
 )
 
 type CreatePlaylistRequest struct {
-	Minute int    `json:"minute" binding:"required,min=1"`
+	commontrack.Params
+	commontrack.Duration
 	Market string `json:"market"`
 }
 
-func CreatePlaylist(c *gin.Context) (string, error) {
+func CreatePlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
 	var json CreatePlaylistRequest
 	if err := c.ShouldBindJSON(&json); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	specifyMs := json.Minute * commontrack.MillisecondsPerMinute
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
 
 	user, err := auth.GetUserWithValidToken(c)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	dbInstance, ok := utils.GetDB(c)
 	if !ok {
-		return "", model.ErrFailedGetDB
-	}
-
-	tracks, err := track.GetTracks(dbInstance, specifyMs, json.Market)
-	if err != nil {
-		slog.Error("failed to get tracks", slog.Any("error", err))
-		return "", err
-	}
-
-	if len(tracks) == 0 {
-		return "", model.ErrNotEnoughTracks
+		return nil, model.ErrFailedGetDB
 	}
 
 	ctx := c.Request.Context()
-	playlist, err := spotify.CreatePlaylist(ctx, user, specifyMs)
+	opts, err := prepareOptions(ctx, dbInstance, user, json.Params)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	slog.Info("creating playlist", slog.Int("duration_ms", specifyMs), slog.Int64("seed", opts.Seed))
 
-	err = spotify.AddItemsPlaylist(ctx, string(playlist.ID), tracks, user)
+	result, err := track.GetTracks(dbInstance, specifyMs, json.Market, opts)
 	if err != nil {
-		database.DeletePlaylists(dbInstance, string(playlist.ID), user.Id)
-		if unfollowErr := spotify.UnfollowPlaylist(ctx, playlist.ID, user); unfollowErr != nil {
-			slog.Error("failed to unfollow playlist", slog.Any("error", unfollowErr))
-		}
-		return "", err
+		slog.Error("failed to get tracks", slog.Any("error", err))
+		return nil, err
 	}
 
-	err = database.SavePlaylist(dbInstance, playlist, user.Id)
+	playlist, err := materialize(ctx, dbInstance, user, result.Tracks, specifyMs)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if err = database.IncrementPlaylistCount(dbInstance, user.Id); err != nil {
-		// Non-fatal: log the error but don't fail the playlist creation
-		slog.Warn("failed to increment playlist count",
-			slog.String("user_id", user.Id),
-			slog.Any("error", err))
+	response := &model.CreatePlaylistResponse{
+		PlaylistID: string(playlist.ID),
+		Seed:       opts.Seed,
+		DeltaMs:    result.TotalMs - specifyMs,
+		Details:    result.Approximation.Details(),
 	}
-
-	return string(playlist.ID), nil
+	if json.FillFromCatalog {
+		response.Tracks = result.Tracks
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
+	}
+	return response, nil
 }
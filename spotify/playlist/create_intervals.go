@@ -0,0 +1,85 @@
+package playlist
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/spotify/auth"
+	"github.com/pp-develop/music-timer-api/spotify/track"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+type CreateIntervalPlaylistRequest struct {
+	commontrack.Params
+	commontrack.IntervalParams
+	Market string `json:"market"`
+}
+
+// CreateIntervalPlaylist は区間ごとに選曲元と再生時間を指定したインターバルプレイリストを作成する
+func CreateIntervalPlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
+	var json CreateIntervalPlaylistRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		return nil, err
+	}
+
+	segments, segmentMs, err := json.Expand()
+	if err != nil {
+		return nil, err
+	}
+	// 固定曲はどの区間に含めるか決められないため受け付けない
+	if len(json.Pinned) > 0 {
+		return nil, model.WithDetails(model.ErrInvalidPinnedTracks, map[string]interface{}{
+			"reason": "not_supported",
+		})
+	}
+
+	user, err := auth.GetUserWithValidToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	ctx := c.Request.Context()
+	opts, err := prepareOptions(ctx, dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("creating interval playlist", slog.Int("segment_count", len(segments)), slog.Int64("seed", opts.Seed))
+
+	tracks, boundaries, err := track.GetIntervalTracks(ctx, dbInstance, segments, segmentMs, json.Market, user.Id, opts)
+	if err != nil {
+		slog.Error("failed to get interval tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	// タイトルは実際の再生時間ではなく、指定された区間の合計時間にする
+	totalMs := 0
+	for _, ms := range segmentMs {
+		totalMs += ms
+	}
+	playlist, err := materialize(ctx, dbInstance, user, tracks, totalMs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.CreatePlaylistResponse{
+		PlaylistID: string(playlist.ID),
+		Seed:       opts.Seed,
+		Segments:   boundaries,
+		DeltaMs:    commontrack.SegmentsDeltaMs(boundaries),
+		Details:    commontrack.SegmentsDetails(boundaries),
+	}
+	if json.FillFromCatalog {
+		response.Tracks = tracks
+	}
+	if json.Debug {
+		response.Diagnostics = commontrack.SegmentsDiagnostics(boundaries)
+	}
+	return response, nil
+}
@@ -0,0 +1,80 @@
+package playlist
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/spotify/auth"
+	"github.com/pp-develop/music-timer-api/spotify/track"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+type CreateMixPlaylistRequest struct {
+	commontrack.Params
+	commontrack.Duration
+	commontrack.MixParams
+	Market string `json:"market"`
+}
+
+// CreateMixPlaylist はお気に入り、フォロー中のアーティスト、グローバルカタログの曲を
+// 指定された配分で混ぜたプレイリストを作成する
+func CreateMixPlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
+	var json CreateMixPlaylistRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		return nil, err
+	}
+
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	parts, err := json.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := auth.GetUserWithValidToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	ctx := c.Request.Context()
+	opts, err := prepareOptions(ctx, dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("creating mix playlist", slog.Int("duration_ms", specifyMs), slog.Any("weights", json.Weights), slog.Int64("seed", opts.Seed))
+
+	result, shares, err := track.GetMixTracks(ctx, dbInstance, parts, specifyMs, json.Market, user.Id, opts)
+	if err != nil {
+		slog.Error("failed to get mix tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	playlist, err := materialize(ctx, dbInstance, user, result.Tracks, specifyMs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.CreatePlaylistResponse{
+		PlaylistID: string(playlist.ID),
+		Seed:       opts.Seed,
+		DeltaMs:    result.TotalMs - specifyMs,
+		Details:    result.Approximation.Details(),
+		Shares:     shares,
+	}
+	if json.FillFromCatalog {
+		response.Tracks = result.Tracks
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
+	}
+	return response, nil
+}
@@ -1,6 +1,7 @@
 package playlist
 
 import (
+	"log/slog"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -13,55 +14,78 @@ import (
 )
 
 type GuestCreatePlaylistRequest struct {
-	Minute int `json:"minute" binding:"required,min=1"`
+	commontrack.Params
+	commontrack.Duration
 }
 
-func GestCreatePlaylist(c *gin.Context) (string, error) {
+func GestCreatePlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
 	var json GuestCreatePlaylistRequest
 	if err := c.ShouldBindJSON(&json); err != nil {
-		return "", err
+		return nil, err
 	}
-	specifyMs := json.Minute * commontrack.MillisecondsPerMinute
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	opts := json.Options()
+	slog.Info("creating playlist", slog.Int("duration_ms", specifyMs), slog.Int64("seed", opts.Seed))
 
 	dbInstance, ok := utils.GetDB(c)
 	if !ok {
-		return "", model.ErrFailedGetDB
-	}
-
-	// DBからトラックを取得
-	tracks, err := track.GetTracks(dbInstance, specifyMs, "")
-	if err != nil {
-		return "", err
+		return nil, model.ErrFailedGetDB
 	}
 
 	user, err := database.GetUser(dbInstance, os.Getenv("SPOTIFY_GEST_ACCOUNT"))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	token, err := spotify.RefreshToken(user)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	user.AccessToken = token.AccessToken
 	user.RefreshToken = token.RefreshToken
 	user.TokenExpiration = token.Expiry.Second()
 
 	ctx := c.Request.Context()
+	opts.Pins, err = track.ResolvePinned(ctx, user, json.Pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	// DBからトラックを取得
+	result, err := track.GetTracks(dbInstance, specifyMs, "", opts)
+	if err != nil {
+		return nil, err
+	}
+
 	playlist, err := spotify.CreatePlaylist(ctx, user, specifyMs)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = spotify.AddItemsPlaylist(ctx, string(playlist.ID), tracks, user)
+	err = spotify.AddItemsPlaylist(ctx, string(playlist.ID), result.Tracks, user)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// TODO:: delete
-	err = database.SavePlaylist(dbInstance, playlist, user.Id)
+	err = database.SavePlaylist(dbInstance, playlist, user.Id, result.Tracks)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(playlist.ID), nil
+	response := &model.CreatePlaylistResponse{
+		PlaylistID: string(playlist.ID),
+		Seed:       opts.Seed,
+		DeltaMs:    result.TotalMs - specifyMs,
+		Details:    result.Approximation.Details(),
+	}
+	if json.FillFromCatalog {
+		response.Tracks = result.Tracks
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
+	}
+	return response, nil
 }
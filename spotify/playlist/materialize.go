@@ -0,0 +1,81 @@
+package playlist
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/pp-develop/music-timer-api/api/spotify"
+	"github.com/pp-develop/music-timer-api/database"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/blocklist"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/spotify/track"
+	spotifylibrary "github.com/zmb3/spotify/v2"
+)
+
+// prepareOptions はリクエストのパラメータから選曲オプションを組み立て、
+// 固定曲の解決、除外リストと最近使った曲の読み込みを行う
+func prepareOptions(ctx context.Context, db *sql.DB, user model.User, params commontrack.Params) (commontrack.Options, error) {
+	opts := params.Options()
+
+	var err error
+	opts.Pins, err = track.ResolvePinned(ctx, user, params.Pinned)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Blocklist, err = blocklist.Load(db, model.ServiceSpotify, user.Id)
+	if err != nil {
+		return opts, err
+	}
+
+	if params.AvoidsRecent() {
+		opts.Recent, err = database.GetRecentPlaylistTracks(db, user.Id, params.AvoidRecent, params.FreshnessDays)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	// お気に入りなどの曲だけで埋まらない隙間は、グローバルカタログの曲で埋める
+	if params.FillFromCatalog {
+		opts.GapFill = track.CatalogGapFill(db, user.Country, opts.Seed)
+	}
+	return opts, nil
+}
+
+// materialize は選曲した曲で Spotify 上にプレイリストを作成し、DBに保存する。
+// 曲の追加に失敗した場合は作成したプレイリストを削除する。
+func materialize(ctx context.Context, db *sql.DB, user model.User, tracks []model.Track, specifyMs int) (*spotifylibrary.FullPlaylist, error) {
+	if len(tracks) == 0 {
+		return nil, model.ErrNotEnoughTracks
+	}
+
+	playlist, err := spotify.CreatePlaylist(ctx, user, specifyMs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = spotify.AddItemsPlaylist(ctx, string(playlist.ID), tracks, user)
+	if err != nil {
+		database.DeletePlaylists(db, string(playlist.ID), user.Id)
+		if unfollowErr := spotify.UnfollowPlaylist(ctx, playlist.ID, user); unfollowErr != nil {
+			slog.Error("failed to unfollow playlist", slog.Any("error", unfollowErr))
+		}
+		return nil, err
+	}
+
+	err = database.SavePlaylist(db, playlist, user.Id, tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = database.IncrementPlaylistCount(db, user.Id); err != nil {
+		// Non-fatal: log the error but don't fail the playlist creation
+		slog.Warn("failed to increment playlist count",
+			slog.String("user_id", user.Id),
+			slog.Any("error", err))
+	}
+
+	return playlist, nil
+}
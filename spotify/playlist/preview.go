@@ -0,0 +1,114 @@
+package playlist
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pp-develop/music-timer-api/model"
+	"github.com/pp-develop/music-timer-api/pkg/common/preview"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+	"github.com/pp-develop/music-timer-api/spotify/auth"
+	"github.com/pp-develop/music-timer-api/spotify/track"
+	"github.com/pp-develop/music-timer-api/utils"
+)
+
+type PreviewPlaylistRequest struct {
+	commontrack.Params
+	commontrack.Duration
+	// Source は選曲元（catalog: POST /playlists, favorites: /from-favorites, artists: /from-artists と同じ）
+	Source    string   `json:"source" binding:"required,oneof=catalog favorites artists"`
+	ArtistIds []string `json:"artistIds"`
+	Market    string   `json:"market"`
+}
+
+// PreviewPlaylist は CreatePlaylist* と同じ選曲を行い、Spotify にはプレイリストを作成せずに結果を返す。
+// 返したトークンを CommitPlaylist に渡すと、同じ曲でプレイリストが作成される。
+func PreviewPlaylist(c *gin.Context) (*model.PreviewPlaylistResponse, error) {
+	var json PreviewPlaylistRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		return nil, err
+	}
+
+	specifyMs, err := json.TotalMs()
+	if err != nil {
+		return nil, err
+	}
+	if json.Source == commontrack.SegmentSourceArtists && len(json.ArtistIds) == 0 {
+		return nil, model.ErrInvalidRequest
+	}
+
+	user, err := auth.GetUserWithValidToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	ctx := c.Request.Context()
+	opts, err := prepareOptions(ctx, dbInstance, user, json.Params)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("previewing playlist", slog.String("source", json.Source), slog.Int("duration_ms", specifyMs), slog.Int64("seed", opts.Seed))
+
+	result, err := selectTracks(dbInstance, user, json.Source, specifyMs, json.ArtistIds, json.Market, opts)
+	if err != nil {
+		slog.Error("failed to get tracks", slog.Any("error", err))
+		return nil, err
+	}
+
+	response, err := preview.Save(dbInstance, model.ServiceSpotify, user.Id, specifyMs, opts.Seed, result)
+	if err != nil {
+		return nil, err
+	}
+	if json.Debug {
+		response.Diagnostics = &result.Diagnostics
+	}
+	return response, nil
+}
+
+// CommitPlaylist はプレビューした曲のとおりに Spotify 上にプレイリストを作成する
+func CommitPlaylist(c *gin.Context) (*model.CreatePlaylistResponse, error) {
+	var json preview.CommitRequest
+	if err := c.ShouldBindJSON(&json); err != nil {
+		return nil, err
+	}
+
+	user, err := auth.GetUserWithValidToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstance, ok := utils.GetDB(c)
+	if !ok {
+		return nil, model.ErrFailedGetDB
+	}
+
+	return preview.Commit(dbInstance, json.PreviewToken, model.ServiceSpotify, user.Id, func(p *model.PlaylistPreview) (*model.CreatePlaylistResponse, error) {
+		playlist, err := materialize(c.Request.Context(), dbInstance, user, p.Tracks, p.TargetMs)
+		if err != nil {
+			return nil, err
+		}
+		return &model.CreatePlaylistResponse{
+			PlaylistID: string(playlist.ID),
+			Seed:       p.Seed,
+			DeltaMs:    p.TotalMs() - p.TargetMs,
+		}, nil
+	})
+}
+
+// selectTracks は選曲元に応じて CreatePlaylist* と同じ方法で選曲する
+func selectTracks(db *sql.DB, user model.User, source string, specifyMs int, artistIds []string, market string, opts commontrack.Options) (*commontrack.Result, error) {
+	switch source {
+	case commontrack.SegmentSourceFavorites:
+		return track.GetFavoriteTracks(db, specifyMs, nil, user.Id, opts)
+	case commontrack.SegmentSourceArtists:
+		return track.GetTracksFromArtists(db, specifyMs, artistIds, user.Id, opts)
+	default:
+		return track.GetTracks(db, specifyMs, market, opts)
+	}
+}
@@ -3,9 +3,8 @@ package track
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
-	"strings"
-	"time"
 
 	"github.com/pp-develop/music-timer-api/model"
 	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
@@ -13,120 +12,64 @@ import (
 )
 
 // GetTracks関数は、指定された総再生時間に基づいてトラックを取得します。
-func GetTracks(db *sql.DB, specify_ms int, market string) ([]model.Track, error) {
-	// Phase 1: データ取得と検証（即座にエラー判定）
-	localTracks, err := json.GetAllTracks(db)
+// opts.Seed はファイルの選択と選曲の両方に使われます。
+func GetTracks(db *sql.DB, specify_ms int, market string, opts commontrack.Options) (*commontrack.Result, error) {
+	// 曲の長さの範囲はカタログの読み込み時に絞り込み、範囲内の曲から抽出する
+	tracksToProcess, err := catalogPool(db, market, opts.Seed, opts.MinTrackMs, opts.MaxTrackMs)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(localTracks) == 0 {
-		// 全トラックが空の場合
-		return nil, model.ErrNotFoundTracks // 即座に返す
+	// Phase 3: 組み合わせ計算（部分和探索）
+	opts.Source = "spotify/catalog"
+	// カタログから選ぶので隙間埋めは不要
+	opts.GapFill = nil
+	opts.LogAttrs = []slog.Attr{slog.String("market", market)}
+	return commontrack.NewSelector(opts).Select(context.Background(), tracksToProcess, specify_ms)
+}
+
+// GetCatalogPool はグローバルカタログから選曲の候補プールを取得する。
+// market が指定されている場合はISRCの国コードが一致する曲だけのファイルを読み、その中から抽出する。
+func GetCatalogPool(db *sql.DB, market string, seed int64) ([]model.Track, error) {
+	return catalogPool(db, market, seed, 0, 0)
+}
+
+// catalogPool は再生時間が [minMs, maxMs] の曲に限って GetCatalogPool と同じ候補プールを取得する
+func catalogPool(db *sql.DB, market string, seed int64, minMs, maxMs int) ([]model.Track, error) {
+	// Phase 1: データ取得とマーケットフィルタリング（即座にエラー判定）
+	localTracks, err := json.SampleTracksInRange(db, seed, market, minMs, maxMs)
+	if err != nil {
+		return nil, err
 	}
 
-	// Phase 2: マーケットフィルタリング（必要な場合）
-	var tracksToProcess []model.Track
-	if market != "" {
-		tracksToProcess = filterByISRC(localTracks, market)
-		if len(tracksToProcess) == 0 {
+	if len(localTracks) == 0 {
+		if market != "" || minMs > 0 || maxMs > 0 {
 			return nil, model.ErrNotEnoughTracks // フィルタ後にトラックがない
 		}
-	} else {
-		tracksToProcess = localTracks
+		// 全トラックが空の場合
+		return nil, model.ErrNotFoundTracks // 即座に返す
 	}
+	return localTracks, nil
+}
 
-	// Phase 3: 組み合わせ計算（時間がかかる可能性がある処理）
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(commontrack.DefaultTimeoutSeconds)*time.Second)
-	defer cancel()
-
-	c1 := make(chan []model.Track, 1)
-	errChan := make(chan error, 1)
-	tryCountChan := make(chan int, 1) // 試行回数を送信するチャネル
-	tryCount := 0                     // 試行回数をカウントする変数
-
-	go func() {
-		defer close(c1)
-		defer close(errChan)
-		defer func() {
-			tryCountChan <- tryCount // goroutine終了時に試行回数を送信
-			close(tryCountChan)
-		}()
-
-		var tracks []model.Track
-		success := false
-		for !success {
-			select {
-			case <-ctx.Done(): // タイムアウトまたはキャンセル時にループを終了
-				errChan <- ctx.Err()
-				return
-			default:
-				tryCount++
-				shuffleTracks := json.ShuffleTracks(tracksToProcess)
-				success, tracks = commontrack.MakeTracks(shuffleTracks, specify_ms)
-			}
+// CatalogGapFill はグローバルカタログから隙間を埋める候補プールを返す関数を作る。
+// country に一致するISRCの曲を優先し、それで埋まらなければカタログ全体から探す。
+func CatalogGapFill(db *sql.DB, country string, seed int64) commontrack.GapFillFunc {
+	return func() ([][]model.Track, error) {
+		all, err := GetCatalogPool(db, "", seed)
+		if err != nil {
+			return nil, err
 		}
-		c1 <- tracks
-	}()
-
-	select {
-	case tracks := <-c1:
-		finalTryCount := <-tryCountChan
-		slog.Info("track selection completed", slog.Int("try_count", finalTryCount))
-		return tracks, nil
-	case err := <-errChan:
-		finalTryCount := <-tryCountChan
-		slog.Warn("track selection timeout", slog.Int("try_count", finalTryCount))
-		return nil, err
-	case <-ctx.Done(): // タイムアウト時
-		finalTryCount := <-tryCountChan
-
-		// トラックの総再生時間を計算
-		totalAvailableDuration := 0
-		for _, track := range tracksToProcess {
-			totalAvailableDuration += track.DurationMs
+		if country == "" {
+			return [][]model.Track{all}, nil
 		}
-
-		hasEnoughDuration := totalAvailableDuration >= specify_ms
-
-		if !hasEnoughDuration {
-			slog.Warn("not enough tracks",
-				slog.Int("required_min", specify_ms/commontrack.MillisecondsPerMinute),
-				slog.Int("available_min", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(tracksToProcess)),
-				slog.Int("try_count", finalTryCount),
-				slog.String("market", market))
-			return nil, model.ErrNotEnoughTracks
-		} else {
-			slog.Warn("combination not found",
-				slog.Int("duration_min", specify_ms/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(tracksToProcess)),
-				slog.Int("total_duration_min", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("try_count", finalTryCount),
-				slog.String("market", market))
-			return nil, model.ErrTimeoutCreatePlaylist
+		preferred, err := GetCatalogPool(db, country, seed)
+		if errors.Is(err, model.ErrNotEnoughTracks) {
+			return [][]model.Track{all}, nil
 		}
-	}
-}
-
-// filterByISRC はISRCの国コードプレフィックスに基づいてトラックをフィルタリングする
-// ISRCの形式: CC-XXX-YY-NNNNN（CCが国コード、例: JP, US, GB）
-// countryCode: 2文字の国コード（例: "JP"）
-func filterByISRC(tracks []model.Track, countryCode string) []model.Track {
-	if len(countryCode) < 2 {
-		return tracks // 無効な国コードの場合は全トラックを返す
-	}
-
-	// 大文字に正規化
-	countryCode = strings.ToUpper(countryCode)
-
-	var filteredTracks []model.Track
-	for _, track := range tracks {
-		// ISRCの先頭2文字が国コード
-		if len(track.Isrc) >= 2 && strings.HasPrefix(strings.ToUpper(track.Isrc), countryCode) {
-			filteredTracks = append(filteredTracks, track)
+		if err != nil {
+			return nil, err
 		}
+		return [][]model.Track{preferred, all}, nil
 	}
-
-	return filteredTracks
 }
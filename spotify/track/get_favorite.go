@@ -4,15 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
-	"time"
 
 	"github.com/pp-develop/music-timer-api/database"
 	"github.com/pp-develop/music-timer-api/model"
 	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
-	"github.com/pp-develop/music-timer-api/spotify/json"
 )
 
-func GetFavoriteTracks(db *sql.DB, specify_ms int, artistIds []string, userId string) ([]model.Track, error) {
+func GetFavoriteTracks(db *sql.DB, specify_ms int, artistIds []string, userId string, opts commontrack.Options) (*commontrack.Result, error) {
+	saveTracks, err := GetFavoritePool(db, artistIds, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Phase 3: 組み合わせ計算（部分和探索）
+	opts.Source = "spotify/favorites"
+	opts.Origin = commontrack.SegmentSourceFavorites
+	opts.LogAttrs = []slog.Attr{slog.Int("artist_count", len(artistIds))}
+	return commontrack.NewSelector(opts).Select(context.Background(), saveTracks, specify_ms)
+}
+
+// GetFavoritePool はユーザーのお気に入りの曲から選曲の候補プールを取得する。
+// artistIds が指定されている場合はそのアーティストの曲に絞り込む。
+func GetFavoritePool(db *sql.DB, artistIds []string, userId string) ([]model.Track, error) {
 	// Phase 1: データ取得と検証（即座にエラー判定）
 	saveTracks, err := database.GetFavoriteTracks(db, userId)
 	if err != nil {
@@ -30,92 +43,7 @@ func GetFavoriteTracks(db *sql.DB, specify_ms int, artistIds []string, userId st
 			return nil, model.ErrNotEnoughTracks // 即座に返す
 		}
 	}
-
-	// Phase 2.5: 総再生時間の事前チェック（早期リターン）
-	totalAvailableDuration := 0
-	for _, track := range saveTracks {
-		totalAvailableDuration += track.DurationMs
-	}
-	if totalAvailableDuration < specify_ms {
-		slog.Warn("not enough favorite tracks (early return)",
-			slog.Int("required_min", specify_ms/commontrack.MillisecondsPerMinute),
-			slog.Int("available_min", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-			slog.Int("track_count", len(saveTracks)),
-			slog.Int("artist_count", len(artistIds)))
-		return nil, model.ErrNotEnoughTracks
-	}
-
-	// Phase 3: 組み合わせ計算（時間がかかる可能性がある処理）
-	var tracks []model.Track
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(commontrack.DefaultTimeoutSeconds)*time.Second)
-	defer cancel()
-
-	c1 := make(chan []model.Track, 1)
-	errChan := make(chan error, 1)
-	tryCountChan := make(chan int, 1) // 試行回数を送信するチャネル
-	tryCount := 0                     // 試行回数をカウントする変数
-
-	go func() {
-		defer close(c1)
-		defer close(errChan)
-		defer func() {
-			tryCountChan <- tryCount // goroutine終了時に試行回数を送信
-			close(tryCountChan)
-		}()
-
-		success := false
-		for !success {
-			select {
-			case <-ctx.Done(): // タイムアウトまたはキャンセル時にループを終了
-				errChan <- ctx.Err()
-				return
-			default:
-				tryCount++
-				shuffleTracks := json.ShuffleTracks(saveTracks)
-				success, tracks = commontrack.MakeTracks(shuffleTracks, specify_ms)
-			}
-		}
-		c1 <- tracks
-	}()
-
-	select {
-	case tracks := <-c1:
-		finalTryCount := <-tryCountChan
-		slog.Info("track selection completed", slog.Int("try_count", finalTryCount))
-		return tracks, nil
-	case err := <-errChan:
-		finalTryCount := <-tryCountChan
-		slog.Warn("track selection timeout", slog.Int("try_count", finalTryCount))
-		return nil, err
-	case <-ctx.Done(): // タイムアウト時
-		finalTryCount := <-tryCountChan
-
-		// トラックの総再生時間を計算
-		totalAvailableDuration := 0
-		for _, track := range saveTracks {
-			totalAvailableDuration += track.DurationMs
-		}
-
-		hasEnoughDuration := totalAvailableDuration >= specify_ms
-
-		if !hasEnoughDuration {
-			slog.Warn("not enough favorite tracks",
-				slog.Int("required_min", specify_ms/commontrack.MillisecondsPerMinute),
-				slog.Int("available_min", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(saveTracks)),
-				slog.Int("try_count", finalTryCount),
-				slog.Int("artist_count", len(artistIds)))
-			return nil, model.ErrNotEnoughTracks
-		} else {
-			slog.Warn("favorite tracks combination not found",
-				slog.Int("duration_min", specify_ms/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(saveTracks)),
-				slog.Int("total_duration_min", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("try_count", finalTryCount),
-				slog.Int("artist_count", len(artistIds)))
-			return nil, model.ErrTimeoutCreatePlaylist
-		}
-	}
+	return saveTracks, nil
 }
 
 // 関数: 特定のアーティストIDを含むトラックをフィルタリング
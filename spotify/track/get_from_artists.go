@@ -4,99 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
-	"time"
 
 	"github.com/pp-develop/music-timer-api/database"
 	"github.com/pp-develop/music-timer-api/model"
 	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
-	"github.com/pp-develop/music-timer-api/spotify/json"
 )
 
-func GetTracksFromArtists(db *sql.DB, specify_ms int, artistIds []string, userId string) ([]model.Track, error) {
+func GetTracksFromArtists(db *sql.DB, specify_ms int, artistIds []string, userId string, opts commontrack.Options) (*commontrack.Result, error) {
 	// Phase 1: データ取得と検証（即座にエラー判定）
-	var artists []model.Artists
-	for _, id := range artistIds {
-		artists = append(artists, model.Artists{Id: id})
-	}
-
-	followedArtistsTracks, err := getSpecifyArtistsAllTracks(db, artists)
+	followedArtistsTracks, err := GetArtistsPool(db, artistIds)
 	if err != nil {
 		return nil, err // ErrNotFoundTracksも含む
 	}
 
-	// Phase 2: 組み合わせ計算（時間がかかる可能性がある処理）
-	var tracks []model.Track
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(commontrack.DefaultTimeoutSeconds)*time.Second)
-	defer cancel()
-
-	c1 := make(chan []model.Track, 1)
-	errChan := make(chan error, 1)
-	tryCountChan := make(chan int, 1) // 試行回数を送信するチャネル
-	tryCount := 0                     // 試行回数をカウントする変数
-
-	go func() {
-		defer close(c1)
-		defer close(errChan)
-		defer func() {
-			tryCountChan <- tryCount // goroutine終了時に試行回数を送信
-			close(tryCountChan)
-		}()
-
-		success := false
-		for !success {
-			select {
-			case <-ctx.Done(): // タイムアウトまたはキャンセル時にループを終了
-				errChan <- ctx.Err()
-				return
-			default:
-				tryCount++
-				shuffleTracks := json.ShuffleTracks(followedArtistsTracks)
-				success, tracks = commontrack.MakeTracks(shuffleTracks, specify_ms)
-			}
-		}
-		c1 <- tracks
-	}()
-
-	select {
-	case tracks := <-c1:
-		finalTryCount := <-tryCountChan
-		slog.Debug("track selection complete", slog.Int("try_count", finalTryCount))
-		return tracks, nil
-	case err := <-errChan:
-		finalTryCount := <-tryCountChan
-		slog.Debug("track selection error", slog.Int("try_count", finalTryCount))
-		return nil, err
-	case <-ctx.Done(): // タイムアウト時
-		finalTryCount := <-tryCountChan
-
-		// トラックの総再生時間を計算
-		totalAvailableDuration := 0
-		for _, track := range followedArtistsTracks {
-			totalAvailableDuration += track.DurationMs
-		}
-
-		hasEnoughDuration := totalAvailableDuration >= specify_ms
+	// Phase 2: 組み合わせ計算（部分和探索）
+	opts.Source = "spotify/artists"
+	opts.Origin = commontrack.SegmentSourceArtists
+	opts.LogAttrs = []slog.Attr{slog.Int("artist_count", len(artistIds))}
+	return commontrack.NewSelector(opts).Select(context.Background(), followedArtistsTracks, specify_ms)
+}
 
-		if !hasEnoughDuration {
-			slog.Warn("timeout: not enough tracks",
-				slog.Int("required_minutes", specify_ms/commontrack.MillisecondsPerMinute),
-				slog.Int("available_minutes", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(followedArtistsTracks)),
-				slog.Int("try_count", finalTryCount),
-				slog.Int("artist_count", len(artistIds)),
-			)
-			return nil, model.ErrNotEnoughTracks
-		} else {
-			slog.Warn("timeout: combination not found",
-				slog.Int("duration_minutes", specify_ms/commontrack.MillisecondsPerMinute),
-				slog.Int("track_count", len(followedArtistsTracks)),
-				slog.Int("total_duration_minutes", totalAvailableDuration/commontrack.MillisecondsPerMinute),
-				slog.Int("try_count", finalTryCount),
-				slog.Int("artist_count", len(artistIds)),
-			)
-			return nil, model.ErrTimeoutCreatePlaylist
-		}
+// GetArtistsPool は指定アーティストの曲から選曲の候補プールを取得する
+func GetArtistsPool(db *sql.DB, artistIds []string) ([]model.Track, error) {
+	var artists []model.Artists
+	for _, id := range artistIds {
+		artists = append(artists, model.Artists{Id: id})
 	}
+	return getSpecifyArtistsAllTracks(db, artists)
 }
 
 func getSpecifyArtistsAllTracks(db *sql.DB, artists []model.Artists) ([]model.Track, error) {
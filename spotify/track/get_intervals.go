@@ -0,0 +1,32 @@
+package track
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// GetIntervalTracks はインターバルプレイリストの各区間を、区間ごとの選曲元から選曲する。
+// 選曲した曲を連結したものと、各区間の境界を返す。
+func GetIntervalTracks(ctx context.Context, db *sql.DB, segments []commontrack.Segment, segmentMs []int, market string, userId string, opts commontrack.Options) ([]model.Track, []model.SegmentBoundary, error) {
+	opts.Source = "spotify/intervals"
+	return commontrack.FillSegments(ctx, segments, segmentMs, opts, SegmentPool(db, market, userId, opts.Seed))
+}
+
+// SegmentPool は区間や配分の選曲元に対応する候補プールを返す関数を作る
+func SegmentPool(db *sql.DB, market string, userId string, seed int64) commontrack.PoolFunc {
+	return func(seg commontrack.Segment) ([]model.Track, error) {
+		switch seg.Source {
+		case commontrack.SegmentSourceCatalog:
+			return GetCatalogPool(db, market, seed)
+		case commontrack.SegmentSourceFavorites:
+			return GetFavoritePool(db, seg.ArtistIds, userId)
+		case commontrack.SegmentSourceArtists:
+			return GetArtistsPool(db, seg.ArtistIds)
+		default:
+			return nil, model.ErrInvalidSegment
+		}
+	}
+}
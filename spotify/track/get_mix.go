@@ -0,0 +1,16 @@
+package track
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// GetMixTracks は選曲元ごとの配分に従って、複数の選曲元の曲を混ぜて選曲する。
+// 選曲結果と選曲元ごとの内訳を返す。
+func GetMixTracks(ctx context.Context, db *sql.DB, parts []commontrack.MixPart, specifyMs int, market string, userId string, opts commontrack.Options) (*commontrack.Result, []model.MixShare, error) {
+	opts.Source = "spotify/mix"
+	return commontrack.FillMix(ctx, parts, specifyMs, opts, SegmentPool(db, market, userId, opts.Seed))
+}
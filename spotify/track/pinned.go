@@ -0,0 +1,36 @@
+package track
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pp-develop/music-timer-api/api/spotify"
+	"github.com/pp-develop/music-timer-api/model"
+	commontrack "github.com/pp-develop/music-timer-api/pkg/common/track"
+)
+
+// ResolvePinned は固定曲の再生時間などを Spotify API から取得する。
+// URI の代わりにトラックIDが指定された場合も受け付ける。
+func ResolvePinned(ctx context.Context, user model.User, pinned []commontrack.PinnedTrack) ([]commontrack.Pin, error) {
+	if len(pinned) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]commontrack.PinnedTrack, len(pinned))
+	uris := make([]string, len(pinned))
+	for i, p := range pinned {
+		if !strings.HasPrefix(p.Uri, "spotify:track:") {
+			p.Uri = "spotify:track:" + p.Uri
+		}
+		normalized[i] = p
+		uris[i] = p.Uri
+	}
+
+	tracks, err := spotify.GetTracks(ctx, user, uris)
+	if err != nil {
+		return nil, err
+	}
+	return commontrack.ResolvePins(normalized, tracks, func(t model.Track) string {
+		return t.Uri
+	})
+}